@@ -0,0 +1,331 @@
+// Package asm assembles CHIP-8 source into ROMs that can be loaded
+// at ROM_ADDR. Mnemonics follow the ones produced by the emulator's
+// disassembler, for example:
+//
+//	SPRITE = 0x20A      ; constants, also written SPRITE EQU 0x20A
+//	include "font.s"    ; paths are relative to the including file
+//	start:              ; labels
+//	    LD I, sprite
+//	    DRW V0, V1, 2
+//	    JMP start
+//	sprite:
+//	    DB 0xFF, 0x81   ; data bytes, DW for 16 bit words
+package asm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bchadwic/chip8/emulator"
+)
+
+// largest rom that fits in memory after ROM_ADDR
+const MAX_ROM_SIZE = emulator.XO_MEM_SIZE - emulator.ROM_ADDR
+
+// operand keywords, these cannot be used as names
+var keywords = map[string]bool{
+	"I": true, "[I]": true, "K": true, "DT": true, "ST": true,
+	"F": true, "HF": true, "B": true, "R": true, "LONG": true,
+}
+
+type position struct {
+	file string
+	line int
+}
+
+// statement is an instruction or data directive
+type statement struct {
+	pos  position
+	op   string
+	args []string
+	addr int
+}
+
+// symbol is a label or a constant, constants are
+// evaluated on first use so they may refer to labels
+type symbol struct {
+	pos       position
+	expr      string
+	value     int
+	resolved  bool
+	resolving bool
+}
+
+type assembler struct {
+	statements []*statement
+	symbols    map[string]*symbol
+	size       int
+	// files currently being included, to catch cycles
+	including map[string]bool
+	errs      ErrorList
+}
+
+// AssembleFile assembles the source file at path
+func AssembleFile(path string) ([]uint8, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Assemble(path, src)
+}
+
+// Assemble assembles src, name is used in errors and
+// to find included files relative to the source
+func Assemble(name string, src []byte) ([]uint8, error) {
+	a := &assembler{
+		symbols:   map[string]*symbol{},
+		including: map[string]bool{},
+	}
+	a.parse(name, string(src))
+	if a.size > MAX_ROM_SIZE {
+		a.errs = append(a.errs, &Error{File: name, Line: 1, Msg: fmt.Sprintf("rom is %d bytes, larger than %d", a.size, MAX_ROM_SIZE)})
+	}
+	if len(a.errs) > 0 {
+		return nil, a.errs
+	}
+
+	rom := make([]uint8, 0, a.size)
+	for _, s := range a.statements {
+		code, err := a.encode(s)
+		if err != nil {
+			a.errorf(s.pos, "%v", err)
+			continue
+		}
+		rom = append(rom, code...)
+	}
+	if len(a.errs) > 0 {
+		return nil, a.errs
+	}
+	return rom, nil
+}
+
+func (a *assembler) errorf(pos position, format string, args ...any) {
+	a.errs = append(a.errs, &Error{File: pos.file, Line: pos.line, Msg: fmt.Sprintf(format, args...)})
+}
+
+// parse splits the source into statements, defining labels
+// and constants and laying out addresses as it goes
+func (a *assembler) parse(name, src string) {
+	a.including[name] = true
+	defer delete(a.including, name)
+
+	for n, line := range strings.Split(src, "\n") {
+		pos := position{file: name, line: n + 1}
+		if c := strings.IndexByte(line, ';'); c >= 0 {
+			line = line[:c]
+		}
+		line = strings.TrimSpace(line)
+
+		// any number of labels can start a line
+		for {
+			label, rest, ok := strings.Cut(line, ":")
+			label = strings.TrimSpace(label)
+			if !ok || strings.ContainsAny(label, " \t,") {
+				break
+			}
+			a.define(pos, label, &symbol{pos: pos, value: emulator.ROM_ADDR + a.size, resolved: true})
+			line = strings.TrimSpace(rest)
+		}
+		if line == "" {
+			continue
+		}
+
+		op, rest, _ := strings.Cut(strings.Join(strings.Fields(line), " "), " ")
+		if name, expr, ok := strings.Cut(line, "="); ok {
+			a.define(pos, strings.TrimSpace(name), &symbol{pos: pos, expr: strings.TrimSpace(expr)})
+			continue
+		}
+		if fields := strings.Fields(rest); len(fields) > 0 && strings.EqualFold(fields[0], "EQU") {
+			a.define(pos, op, &symbol{pos: pos, expr: strings.TrimSpace(rest[len(fields[0]):])})
+			continue
+		}
+		if strings.EqualFold(op, "INCLUDE") {
+			a.include(pos, rest)
+			continue
+		}
+
+		s := &statement{pos: pos, op: strings.ToUpper(op), addr: emulator.ROM_ADDR + a.size}
+		if rest != "" {
+			for _, arg := range strings.Split(rest, ",") {
+				s.args = append(s.args, strings.TrimSpace(arg))
+			}
+		}
+		a.statements = append(a.statements, s)
+		a.size += size(s)
+	}
+}
+
+// include parses another file in place
+func (a *assembler) include(pos position, arg string) {
+	path := strings.Trim(strings.TrimSpace(arg), `"`)
+	if path == "" {
+		a.errorf(pos, "include expects a file name")
+		return
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(pos.file), path)
+	}
+	if a.including[path] {
+		a.errorf(pos, "%s includes itself", path)
+		return
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		a.errorf(pos, "could not include %s: %v", path, err)
+		return
+	}
+	a.parse(path, string(src))
+}
+
+func (a *assembler) define(pos position, name string, s *symbol) {
+	if !isName(name) {
+		a.errorf(pos, "invalid name %q", name)
+		return
+	}
+	if !s.resolved && s.expr == "" {
+		a.errorf(pos, "%s is missing an expression", name)
+		return
+	}
+	if prev, ok := a.symbols[name]; ok {
+		a.errorf(pos, "%s already defined at %s:%d", name, prev.pos.file, prev.pos.line)
+		return
+	}
+	a.symbols[name] = s
+}
+
+// isName reports whether s can name a label or constant
+func isName(s string) bool {
+	if s == "" || keywords[strings.ToUpper(s)] {
+		return false
+	}
+	if _, ok := register(s); ok {
+		return false
+	}
+	for i, c := range s {
+		letter := c == '_' || c == '.' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if !letter && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// size returns the number of bytes a statement assembles to
+func size(s *statement) int {
+	switch s.op {
+	case "DB":
+		return len(s.args)
+	case "DW":
+		return 2 * len(s.args)
+	case "LD":
+		if len(s.args) == 2 && isLong(s.args[1]) {
+			return 4
+		}
+	}
+	return 2
+}
+
+func isLong(arg string) bool {
+	fields := strings.Fields(arg)
+	return len(fields) > 1 && strings.EqualFold(fields[0], "LONG")
+}
+
+// register parses a register name like V3
+func register(arg string) (uint16, bool) {
+	if len(arg) != 2 || (arg[0] != 'V' && arg[0] != 'v') {
+		return 0, false
+	}
+	x, err := strconv.ParseUint(arg[1:], 16, 4)
+	return uint16(x), err == nil
+}
+
+// value evaluates an expression of numbers and names
+// joined by + and -, numbers can be decimal, hex
+// prefixed with 0x or $, or binary prefixed with 0b
+func (a *assembler) value(expr string) (int, error) {
+	expr = strings.ReplaceAll(expr, " ", "")
+	if expr == "" {
+		return 0, fmt.Errorf("missing value")
+	}
+	total, sign, start := 0, 1, 0
+	for i := 0; i <= len(expr); i++ {
+		if i < len(expr) && (expr[i] != '+' && expr[i] != '-' || i == start) {
+			continue
+		}
+		term := expr[start:i]
+		if strings.HasPrefix(term, "-") {
+			sign, term = -sign, term[1:]
+		}
+		v, err := a.term(term)
+		if err != nil {
+			return 0, err
+		}
+		total += sign * v
+		if i < len(expr) && expr[i] == '-' {
+			sign = -1
+		} else {
+			sign = 1
+		}
+		start = i + 1
+	}
+	return total, nil
+}
+
+func (a *assembler) term(term string) (int, error) {
+	if term == "" {
+		return 0, fmt.Errorf("missing value")
+	}
+	if c := term[0]; c >= '0' && c <= '9' || c == '$' {
+		digits, base := term, 10
+		switch {
+		case strings.HasPrefix(term, "0x"), strings.HasPrefix(term, "0X"):
+			digits, base = term[2:], 16
+		case strings.HasPrefix(term, "0b"), strings.HasPrefix(term, "0B"):
+			digits, base = term[2:], 2
+		case c == '$':
+			digits, base = term[1:], 16
+		}
+		v, err := strconv.ParseInt(digits, base, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number %s", term)
+		}
+		return int(v), nil
+	}
+
+	s, ok := a.symbols[term]
+	if !ok {
+		return 0, fmt.Errorf("undefined name %s", term)
+	}
+	if !s.resolved {
+		if s.resolving {
+			return 0, fmt.Errorf("%s is defined in terms of itself", term)
+		}
+		s.resolving = true
+		v, err := a.value(s.expr)
+		s.resolving = false
+		if err != nil {
+			return 0, fmt.Errorf("in %s: %v", term, err)
+		}
+		s.value, s.resolved = v, true
+	}
+	return s.value, nil
+}
+
+// number evaluates expr, checking it fits in max
+func (a *assembler) number(expr string, max int) (uint16, error) {
+	v, err := a.value(expr)
+	if err != nil {
+		return 0, err
+	}
+	// negative bytes are accepted as two's complement
+	if max == 0xFF && v < 0 && v >= -0x80 {
+		v += 0x100
+	}
+	if v < 0 || v > max {
+		return 0, fmt.Errorf("%s is out of range 0x0-0x%X", expr, max)
+	}
+	return uint16(v), nil
+}
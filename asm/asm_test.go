@@ -0,0 +1,139 @@
+package asm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bchadwic/chip8/disasm"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Assemble(t *testing.T) {
+	src := `
+; draws a sprite forever
+X = 0x0C
+Y EQU X - 4
+start:
+	LD V0, X
+	LD V1, Y
+	LD I, sprite
+	CALL draw
+loop: JMP loop
+draw:
+	DRW V0, V1, sprite_end - sprite
+	RET
+sprite:
+	db 0xFF, 0x81, $42, 0b00011000
+sprite_end:
+	dw 0x1234
+`
+	rom, err := Assemble("test.s", []byte(src))
+	assert.NoError(t, err)
+	assert.Equal(t, []uint8{
+		0x60, 0x0C, // 0x200: LD V0, 0x0C
+		0x61, 0x08, // 0x202: LD V1, 0x08
+		0xA2, 0x0E, // 0x204: LD I, 0x20E
+		0x22, 0x0A, // 0x206: CALL 0x20A
+		0x12, 0x08, // 0x208: JMP 0x208
+		0xD0, 0x14, // 0x20A: DRW V0, V1, 4
+		0x00, 0xEE, // 0x20C: RET
+		0xFF, 0x81, 0x42, 0x18,
+		0x12, 0x34,
+	}, rom)
+}
+
+func Test_Assemble_instructions(t *testing.T) {
+	cases := map[string][]uint8{
+		"CLS":               {0x00, 0xE0},
+		"scd 4":             {0x00, 0xC4},
+		"JMP V0, 0x300":     {0xB3, 0x00},
+		"SEQ V3, VA":        {0x53, 0xA0},
+		"SNE V3, K":         {0xE3, 0xA1},
+		"SAVE V1, V4":       {0x51, 0x42},
+		"SHR V2":            {0x82, 0x26},
+		"SUBN V2, V3":       {0x82, 0x37},
+		"ADD I, V5":         {0xF5, 0x1E},
+		"LD [I], V5":        {0xF5, 0x55},
+		"LD V5, [ I ]":      {0xF5, 0x65},
+		"LD HF, V1":         {0xF1, 0x30},
+		"LD I, LONG 0x1234": {0xF0, 0x00, 0x12, 0x34},
+		"PLANE 3":           {0xF3, 0x01},
+		"AUDIO":             {0xF0, 0x02},
+		"ADD V1, -1":        {0x71, 0xFF},
+	}
+	for src, code := range cases {
+		rom, err := Assemble("test.s", []byte(src))
+		assert.NoError(t, err, src)
+		assert.Equal(t, code, rom, src)
+	}
+}
+
+func Test_Assemble_errors(t *testing.T) {
+	src := `
+	LD V0, 0x100
+	JMP nowhere
+	FOO V1
+a:
+a:
+	JMP V1, 0x200
+`
+	_, err := Assemble("test.s", []byte(src))
+	assert.Equal(t, ErrorList{
+		{File: "test.s", Line: 6, Msg: "a already defined at test.s:5"},
+	}, err)
+
+	_, err = Assemble("test.s", []byte(strings.Replace(src, "a:\n", "", 1)))
+	assert.Equal(t, ""+
+		"test.s:2: 0x100 is out of range 0x0-0xFF\n"+
+		"test.s:3: undefined name nowhere\n"+
+		"test.s:4: unknown instruction FOO V1\n"+
+		"test.s:6: JMP can only offset by V0", err.Error())
+}
+
+func Test_Assemble_missing(t *testing.T) {
+	_, err := Assemble("test.s", []byte("a =\nc EQU\n"))
+	assert.Equal(t, ""+
+		"test.s:1: a is missing an expression\n"+
+		"test.s:2: c is missing an expression", err.Error())
+
+	_, err = Assemble("test.s", []byte("LD I, LONG\n"))
+	assert.Equal(t, "test.s:1: LONG expects an address", err.Error())
+}
+
+func Test_Assemble_include(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "main.s"), []byte("include \"lib/sprite.s\"\nLD I, sprite\n"), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "lib"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "lib", "sprite.s"), []byte("sprite: db 0xF0\n"), 0644))
+
+	rom, err := AssembleFile(filepath.Join(dir, "main.s"))
+	assert.NoError(t, err)
+	assert.Equal(t, []uint8{0xF0, 0xA2, 0x00}, rom)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "lib", "sprite.s"), []byte("include \"../main.s\"\n"), 0644))
+	_, err = AssembleFile(filepath.Join(dir, "main.s"))
+	assert.ErrorContains(t, err, "sprite.s:1: "+filepath.Join(dir, "main.s")+" includes itself")
+}
+
+// disassembling then assembling a rom gives back the same rom
+func Test_Assemble_roundTrip(t *testing.T) {
+	roms, err := filepath.Glob("../roms/*.ch8")
+	assert.NoError(t, err)
+	for _, path := range roms {
+		rom, err := os.ReadFile(path)
+		assert.NoError(t, err)
+
+		var src strings.Builder
+		for _, l := range disasm.Disassemble(rom) {
+			if l.Label != "" {
+				src.WriteString(l.Label + ":\n")
+			}
+			src.WriteString("\t" + l.Text + "\n")
+		}
+		assembled, err := Assemble(path, []byte(src.String()))
+		assert.NoError(t, err, path)
+		assert.Equal(t, rom, assembled, path)
+	}
+}
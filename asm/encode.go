@@ -0,0 +1,227 @@
+package asm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bchadwic/chip8/emulator"
+)
+
+// shapes of operands, keywords stand for themselves
+const (
+	REG = "V"
+	NUM = "N"
+)
+
+// operands classifies the arguments of a statement, returning
+// their shape such as "V,N" along with the registers and
+// expressions found in order
+func operands(args []string) (string, []uint16, []string) {
+	var shape []string
+	var regs []uint16
+	var exprs []string
+	for _, arg := range args {
+		if x, ok := register(arg); ok {
+			shape = append(shape, REG)
+			regs = append(regs, x)
+		} else if upper := strings.ToUpper(strings.ReplaceAll(arg, " ", "")); keywords[upper] {
+			shape = append(shape, upper)
+		} else if isLong(arg) {
+			shape = append(shape, "LONG")
+			exprs = append(exprs, strings.TrimSpace(arg[len("LONG"):]))
+		} else {
+			shape = append(shape, NUM)
+			exprs = append(exprs, arg)
+		}
+	}
+	return strings.Join(shape, ","), regs, exprs
+}
+
+var modOps = map[string]uint16{
+	"OR":   emulator.OR_VX_VY,
+	"AND":  emulator.AND_VX_VY,
+	"XOR":  emulator.XOR_VX_VY,
+	"SUB":  emulator.SUB_VX_VY,
+	"SHR":  emulator.SHR_VX_VY,
+	"SUBN": emulator.SUBN_VX_VY,
+	"SHL":  emulator.SHL_VX_VY,
+}
+
+// instructions whose operands are all registers
+var registerOps = map[string]uint16{
+	"SEQ V,V":  emulator.SEQ_VX_VY,
+	"SNE V,V":  emulator.SNE_VX_VY,
+	"SAVE V,V": emulator.SEQ_VX_VY | emulator.SAVE_VX_VY,
+	"LOAD V,V": emulator.SEQ_VX_VY | emulator.LOAD_VX_VY,
+	"LD V,V":   emulator.MOD_VX_VY_OPS | emulator.LD_VX_VY,
+	"ADD V,V":  emulator.MOD_VX_VY_OPS | emulator.ADD_VX_VY,
+	"SEQ V,K":  emulator.VX_KEY_OPS | emulator.SEQ_VX_KEY_PR,
+	"SNE V,K":  emulator.VX_KEY_OPS | emulator.SNE_VX_KEY_PR,
+	"LD V,DT":  emulator.TIMING_OPS | emulator.LD_VX_DT,
+	"LD V,K":   emulator.TIMING_OPS | emulator.LD_VX_K,
+	"LD DT,V":  emulator.TIMING_OPS | emulator.LD_DT_VX,
+	"LD ST,V":  emulator.TIMING_OPS | emulator.LD_ST_VX,
+	"ADD I,V":  emulator.TIMING_OPS | emulator.ADD_I_VX,
+	"LD F,V":   emulator.TIMING_OPS | emulator.LD_F_VX,
+	"LD HF,V":  emulator.TIMING_OPS | emulator.LD_HF_VX,
+	"LD B,V":   emulator.TIMING_OPS | emulator.LD_B_VX,
+	"LD [I],V": emulator.TIMING_OPS | emulator.LD_I_VX,
+	"LD V,[I]": emulator.TIMING_OPS | emulator.LD_VX_I,
+	"LD R,V":   emulator.TIMING_OPS | emulator.LD_R_VX,
+	"LD V,R":   emulator.TIMING_OPS | emulator.LD_VX_R,
+	"PITCH V":  emulator.TIMING_OPS | emulator.PITCH_VX,
+}
+
+// instructions taking a register and a byte
+var byteOps = map[string]uint16{
+	"SEQ V,N": emulator.SEQ_VX_NN,
+	"SNE V,N": emulator.SNE_VX_NN,
+	"LD V,N":  emulator.LD_VX_KK,
+	"ADD V,N": emulator.ADD_VX_KK,
+	"RND V,N": emulator.RND_VX_KK,
+}
+
+// instructions taking a 12 bit address
+var addrOps = map[string]uint16{
+	"JMP N":   emulator.JMP,
+	"CALL N":  emulator.CALL,
+	"LD I,N":  emulator.LD_I,
+	"JMP V,N": emulator.JMP_V0,
+}
+
+// instructions taking a nibble
+var nibbleOps = map[string]uint16{
+	"SCD N":   emulator.SCD_N,
+	"SCU N":   emulator.SCU_N,
+	"PLANE N": emulator.TIMING_OPS | emulator.PLANE_N,
+}
+
+var fixedOps = map[string]uint16{
+	"CLS":   emulator.CLS,
+	"RET":   emulator.RET,
+	"SCR":   emulator.SCR,
+	"SCL":   emulator.SCL,
+	"EXIT":  emulator.EXIT,
+	"LOW":   emulator.LOW,
+	"HIGH":  emulator.HIGH,
+	"AUDIO": emulator.TIMING_OPS | emulator.AUDIO,
+}
+
+// encode assembles a single statement into bytes
+func (a *assembler) encode(s *statement) ([]uint8, error) {
+	switch s.op {
+	case "DB", "DW":
+		if len(s.args) == 0 {
+			return nil, fmt.Errorf("%s expects at least one value", s.op)
+		}
+		var data []uint8
+		for _, arg := range s.args {
+			if s.op == "DB" {
+				v, err := a.number(arg, 0xFF)
+				if err != nil {
+					return nil, err
+				}
+				data = append(data, uint8(v))
+			} else {
+				v, err := a.number(arg, 0xFFFF)
+				if err != nil {
+					return nil, err
+				}
+				data = append(data, uint8(v>>8), uint8(v))
+			}
+		}
+		return data, nil
+	}
+
+	insts, err := a.instruction(s)
+	if err != nil {
+		return nil, err
+	}
+	var code []uint8
+	for _, inst := range insts {
+		code = append(code, uint8(inst>>8), uint8(inst))
+	}
+	return code, nil
+}
+
+// instruction assembles a statement into one or two opcodes
+func (a *assembler) instruction(s *statement) ([]uint16, error) {
+	shape, regs, exprs := operands(s.args)
+	form := strings.TrimSpace(s.op + " " + shape)
+	var x, y uint16
+	if len(regs) > 0 {
+		x = regs[0] << 8
+	}
+	if len(regs) > 1 {
+		y = regs[1] << 4
+	}
+
+	if inst, ok := fixedOps[form]; ok {
+		return []uint16{inst}, nil
+	}
+	if op, ok := modOps[s.op]; ok {
+		switch shape {
+		case "V,V":
+			return []uint16{emulator.MOD_VX_VY_OPS | x | y | op}, nil
+		case "V":
+			if s.op == "SHR" || s.op == "SHL" {
+				return []uint16{emulator.MOD_VX_VY_OPS | x | regs[0]<<4 | op}, nil
+			}
+		}
+	}
+
+	if op, ok := registerOps[form]; ok {
+		return []uint16{op | x | y}, nil
+	}
+	if op, ok := byteOps[form]; ok {
+		nn, err := a.number(exprs[0], 0xFF)
+		if err != nil {
+			return nil, err
+		}
+		return []uint16{op | x | nn}, nil
+	}
+	if op, ok := addrOps[form]; ok {
+		if form == "JMP V,N" && regs[0] != 0 {
+			return nil, fmt.Errorf("JMP can only offset by V0")
+		}
+		addr, err := a.number(exprs[0], 0xFFF)
+		if err != nil {
+			return nil, err
+		}
+		return []uint16{op | addr}, nil
+	}
+	if op, ok := nibbleOps[form]; ok {
+		n, err := a.number(exprs[0], 0xF)
+		if err != nil {
+			return nil, err
+		}
+		// planes are selected by x rather than n
+		if s.op == "PLANE" {
+			n <<= 8
+		}
+		return []uint16{op | n}, nil
+	}
+
+	switch form {
+	case "LD I,LONG":
+		if len(exprs) == 0 {
+			return nil, fmt.Errorf("LONG expects an address")
+		}
+		addr, err := a.number(exprs[0], 0xFFFF)
+		if err != nil {
+			return nil, err
+		}
+		return []uint16{emulator.TIMING_OPS | emulator.LD_I_LONG, addr}, nil
+	case "DRW V,V,N":
+		n, err := a.number(exprs[0], 0xF)
+		if err != nil {
+			return nil, err
+		}
+		return []uint16{emulator.DRW_VX_VY_N | x | y | n}, nil
+	}
+
+	if len(s.args) == 0 {
+		return nil, fmt.Errorf("unknown instruction %s", s.op)
+	}
+	return nil, fmt.Errorf("unknown instruction %s %s", s.op, strings.Join(s.args, ", "))
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bchadwic/chip8/asm"
+	"github.com/bchadwic/chip8/conformance"
+	"github.com/bchadwic/chip8/disasm"
+	"github.com/bchadwic/chip8/emulator"
+	"github.com/bchadwic/chip8/internal/display"
+	"github.com/bchadwic/chip8/lockstep"
+	"github.com/bchadwic/chip8/movie"
+)
+
+// commands run in place of the emulator when
+// named by the first command line argument
+var commands = map[string]func(args []string) error{
+	"disasm":   disasmCommand,
+	"asm":      asmCommand,
+	"lockstep": lockstepCommand,
+	"test":     testCommand,
+}
+
+// disasmCommand prints a listing of a rom
+func disasmCommand(args []string) error {
+	fs := flag.NewFlagSet("disasm", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of chip8 disasm:\n  chip8 disasm [flags] rom.ch8\n")
+		fs.PrintDefaults()
+	}
+	out := fs.String("o", "", "write the listing to a file rather than stdout")
+	fs.Parse(args)
+
+	if fs.Arg(0) == "" {
+		return fmt.Errorf("rom file not specified")
+	}
+	rom, err := readROM(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		if w, err = os.Create(*out); err != nil {
+			return err
+		}
+		defer w.Close()
+	}
+	b := bufio.NewWriter(w)
+	if err := disasm.Fprint(b, disasm.Disassemble(rom)); err != nil {
+		return err
+	}
+	return b.Flush()
+}
+
+// asmCommand assembles a source file into a rom
+func asmCommand(args []string) error {
+	fs := flag.NewFlagSet("asm", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of chip8 asm:\n  chip8 asm [flags] source.s\n")
+		fs.PrintDefaults()
+	}
+	out := fs.String("o", "", "rom file to write, defaults to the source with a .ch8 extension")
+	fs.Parse(args)
+
+	src := fs.Arg(0)
+	if src == "" {
+		return fmt.Errorf("source file not specified")
+	}
+	rom, err := asm.AssembleFile(src)
+	if err != nil {
+		// errors are already prefixed with their file and line
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *out == "" {
+		*out = strings.TrimSuffix(src, filepath.Ext(src)) + ".ch8"
+	}
+	if *out == src {
+		return fmt.Errorf("refusing to overwrite the source file %s", src)
+	}
+	return os.WriteFile(*out, rom, 0644)
+}
+
+// lockstepCommand runs a rom under two sets of quirks, reporting
+// the first instruction where the machines disagree
+func lockstepCommand(args []string) error {
+	fs := flag.NewFlagSet("lockstep", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of chip8 lockstep:\n  chip8 lockstep [flags] rom.ch8\n")
+		fs.PrintDefaults()
+	}
+	platform := fs.String("platform", "chip8", "instruction set to run (chip8, schip, xochip)")
+	quirksA := fs.String("a", "", "quirks preset of the first machine, defaults to the platform's")
+	quirksB := fs.String("b", "", "quirks preset of the second machine, defaults to the platform's")
+	frames := fs.Int("frames", 600, "frames to run for")
+	seed := fs.Uint64("seed", 1, "seed for random numbers")
+	window := fs.Int("window", lockstep.DEFAULT_WINDOW, "instructions shown leading up to a divergence")
+	input := fs.String("movie", "", "movie to feed both machines input from")
+	fs.Parse(args)
+
+	if fs.Arg(0) == "" {
+		return fmt.Errorf("rom file not specified")
+	}
+	rom, err := readROM(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var m *movie.Movie
+	if *input != "" {
+		f, err := os.Open(*input)
+		if err != nil {
+			return fmt.Errorf("could not open movie: %v", err)
+		}
+		m, err = movie.Read(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	machine := func(preset string) (emulator.Machine, error) {
+		settings := &emulator.EmulatorSettings{Headless: true, Seed: *seed}
+		if m != nil {
+			if err := m.Settings.Apply(settings, rom); err != nil {
+				return nil, err
+			}
+			settings.Keypad = movie.Play(m)
+		} else if settings.Platform, err = emulator.ParsePlatform(*platform); err != nil {
+			return nil, err
+		}
+		settings.Quirks = settings.Platform.Quirks()
+		if preset != "" {
+			if settings.Quirks, err = emulator.QuirksPreset(preset); err != nil {
+				return nil, err
+			}
+		}
+		em := emulator.Create(settings)
+		return em, em.LoadROM(rom)
+	}
+	a, err := machine(*quirksA)
+	if err != nil {
+		return err
+	}
+	b, err := machine(*quirksB)
+	if err != nil {
+		return err
+	}
+
+	r := lockstep.Create(a, b)
+	r.Window = *window
+	if d := r.Run(*frames); d != nil {
+		fmt.Print(d.Report())
+		os.Exit(1)
+	}
+	fmt.Printf("machines agreed for %d frames\n", *frames)
+	return nil
+}
+
+// testCommand runs the conformance test roms, comparing
+// the screens they end on against golden images
+func testCommand(args []string) error {
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of chip8 test:\n  chip8 test [flags] [case...]\n")
+		fs.PrintDefaults()
+	}
+	roms := fs.String("roms", filepath.Join("conformance", "testdata", "roms"), "directory holding the test suite roms")
+	golden := fs.String("golden", filepath.Join("conformance", "testdata"), "directory holding the golden images")
+	update := fs.Bool("update", false, "write the screens the roms end on as the golden images")
+	fs.Parse(args)
+
+	only := make(map[string]bool)
+	for _, name := range fs.Args() {
+		only[name] = true
+	}
+	failed := 0
+	for _, c := range conformance.Suite {
+		if len(only) > 0 && !only[c.Name] {
+			continue
+		}
+		got, err := c.Run(*roms)
+		if errors.Is(err, conformance.ErrMissing) {
+			fmt.Printf("SKIP %s: %v\n", c.Name, err)
+			continue
+		}
+		if err != nil {
+			fmt.Printf("FAIL %s: %v\n", c.Name, err)
+			failed++
+			continue
+		}
+		if *update {
+			if err := c.Update(*golden, got); err != nil {
+				return err
+			}
+			fmt.Printf("UPDATED %s\n%s", c.Name, got)
+			continue
+		}
+		want, err := c.Golden(*golden)
+		if errors.Is(err, conformance.ErrMissing) {
+			fmt.Printf("SKIP %s: %v, run with -update to create it\n", c.Name, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if diff := display.Diff(want, got); diff != "" {
+			fmt.Printf("FAIL %s\n%s", c.Name, diff)
+			failed++
+			continue
+		}
+		fmt.Printf("PASS %s\n", c.Name)
+	}
+	if failed > 0 {
+		fmt.Printf("%d failed\n", failed)
+		os.Exit(1)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Package conformance runs the community CHIP-8 test roms headlessly
+// and compares the screen they end on against golden images. The
+// roms are not distributed with this repository, they are found at
+// https://github.com/Timendus/chip8-test-suite and are looked up by
+// file name in a rom directory.
+package conformance
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bchadwic/chip8/emulator"
+	"github.com/bchadwic/chip8/internal/display"
+)
+
+// address the test suite reads to skip its menu
+const SELECT_ADDR = 0x1FF
+
+// ErrMissing is returned when a case's rom or golden image does not exist
+var ErrMissing = errors.New("missing")
+
+// Case is a test rom and how long to run it for
+type Case struct {
+	Name string
+	// file name of the rom within the rom directory
+	ROM      string
+	Platform emulator.Platform
+	// value written to SELECT_ADDR before running, 0 leaves it alone
+	Select uint8
+	Frames int
+}
+
+// Suite is every case of the test suite, the quirks test is run once per platform
+var Suite = []Case{
+	{Name: "chip8-logo", ROM: "1-chip8-logo.ch8", Frames: 60},
+	{Name: "ibm-logo", ROM: "2-ibm-logo.ch8", Frames: 60},
+	{Name: "corax", ROM: "3-corax+.ch8", Frames: 120},
+	{Name: "flags", ROM: "4-flags.ch8", Frames: 120},
+	{Name: "quirks-chip8", ROM: "5-quirks.ch8", Select: 1, Frames: 900},
+	{Name: "quirks-schip", ROM: "5-quirks.ch8", Platform: emulator.PLATFORM_SCHIP, Select: 2, Frames: 900},
+	{Name: "quirks-xochip", ROM: "5-quirks.ch8", Platform: emulator.PLATFORM_XOCHIP, Select: 3, Frames: 900},
+	// EX9E with nothing pressed, the FX0A tests need input
+	{Name: "keypad", ROM: "6-keypad.ch8", Select: 1, Frames: 60},
+}
+
+// Run runs the case's rom from romDir, returning the screen it ends on
+func (c Case) Run(romDir string) (string, error) {
+	rom, err := os.ReadFile(filepath.Join(romDir, c.ROM))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("rom %s: %w", c.ROM, ErrMissing)
+	}
+	if err != nil {
+		return "", err
+	}
+	return c.RunROM(rom)
+}
+
+// RunROM runs rom for the case's frames, returning the screen it ends on
+func (c Case) RunROM(rom []uint8) (string, error) {
+	em := emulator.Create(&emulator.EmulatorSettings{
+		Headless: true,
+		Platform: c.Platform,
+		Quirks:   c.Platform.Quirks(),
+		// the suite does not use random numbers
+		Seed: 1,
+	})
+	if err := em.LoadROM(rom); err != nil {
+		return "", err
+	}
+	if c.Select != 0 {
+		em.SetMemory(SELECT_ADDR, c.Select)
+	}
+	for f := 0; f < c.Frames && !em.Halted(); f++ {
+		if err := em.RunFrame(); err != nil {
+			return "", err
+		}
+	}
+	if f := em.Fault(); f != nil {
+		return "", f
+	}
+	return display.Text(em.Display()), nil
+}
+
+// Golden reads the case's golden image from dir
+func (c Case) Golden(dir string) (string, error) {
+	golden, err := os.ReadFile(c.goldenPath(dir))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("golden image %s: %w", c.goldenPath(dir), ErrMissing)
+	}
+	return string(golden), err
+}
+
+// Update writes screen as the case's golden image in dir
+func (c Case) Update(dir, screen string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.goldenPath(dir), []byte(screen), 0o644)
+}
+
+func (c Case) goldenPath(dir string) string {
+	return filepath.Join(dir, c.Name+".txt")
+}
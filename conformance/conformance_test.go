@@ -0,0 +1,76 @@
+package conformance
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bchadwic/chip8/internal/display"
+	"github.com/stretchr/testify/assert"
+)
+
+// the test roms can be put in testdata/roms or anywhere CHIP8_TEST_ROMS
+// points to, roms are only required once CHIP8_TEST_ROMS is set
+func romDir() (string, bool) {
+	if dir := os.Getenv("CHIP8_TEST_ROMS"); dir != "" {
+		return dir, true
+	}
+	return "testdata/roms", false
+}
+
+func Test_Suite(t *testing.T) {
+	dir, required := romDir()
+	for _, c := range Suite {
+		t.Run(c.Name, func(t *testing.T) {
+			got, err := c.Run(dir)
+			if errors.Is(err, ErrMissing) && !required {
+				t.Skipf("%v, the roms are not distributed with chip8", err)
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			// a golden image is checked by hand before it is committed,
+			// so one missing is a failure rather than something to skip
+			want, err := c.Golden("testdata")
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Empty(t, display.Diff(want, got))
+		})
+	}
+}
+
+func Test_RunROM(t *testing.T) {
+	rom := []uint8{
+		0xA1, 0xFF, // LD I, 0x1FF
+		0xF0, 0x65, // LD V0, [I]
+		0xF0, 0x29, // LD F, V0
+		0xD1, 0x15, // DRW V1, V1, 5
+		0x12, 0x08, // JMP 0x208
+	}
+	c := Case{Name: "select", Select: 7, Frames: 2}
+	got, err := c.RunROM(rom)
+	assert.NoError(t, err)
+
+	rows := strings.Split(got, "\n")
+	assert.Len(t, rows, 33)
+	assert.Equal(t, "####....", rows[0][:8])
+	assert.Equal(t, "...#....", rows[1][:8])
+	assert.Equal(t, "..#.....", rows[2][:8])
+	assert.Equal(t, ".#......", rows[3][:8])
+	assert.Equal(t, ".#......", rows[4][:8])
+	assert.Equal(t, strings.Repeat(".", 64), rows[5])
+}
+
+func Test_Golden(t *testing.T) {
+	dir := t.TempDir()
+	c := Case{Name: "golden"}
+	_, err := c.Golden(dir)
+	assert.ErrorIs(t, err, ErrMissing)
+
+	assert.NoError(t, c.Update(dir, "#.\n.#\n"))
+	golden, err := c.Golden(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "#.\n.#\n", golden)
+}
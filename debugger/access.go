@@ -0,0 +1,143 @@
+package debugger
+
+import (
+	"github.com/bchadwic/chip8/emulator"
+)
+
+type kind int
+
+const (
+	REGISTER kind = iota
+	INDEX
+	DELAY
+	SOUND
+	MEMORY
+)
+
+// access is a range of registers or memory touched by an instruction
+type access struct {
+	kind   kind
+	lo, hi uint16
+}
+
+func (a access) overlaps(b access) bool {
+	return a.kind == b.kind && a.lo <= b.hi && b.lo <= a.hi
+}
+
+func reg(x uint16) access {
+	return access{kind: REGISTER, lo: x, hi: x}
+}
+
+func regs(x uint16, y uint16) access {
+	if x > y {
+		x, y = y, x
+	}
+	return access{kind: REGISTER, lo: x, hi: y}
+}
+
+func mem(addr uint16, n uint16) access {
+	return access{kind: MEMORY, lo: addr, hi: addr + n - 1}
+}
+
+var (
+	index = access{kind: INDEX}
+	delay = access{kind: DELAY}
+	sound = access{kind: SOUND}
+	vf    = reg(0xF)
+)
+
+// accesses decodes which registers and memory inst will read and
+// write when executed by m, before it runs. Stack and display
+// accesses are not tracked, nor are changes to i caused by quirks.
+func accesses(m emulator.Machine, inst uint16) (reads []access, writes []access) {
+	x := (inst & emulator.N2_MASK) >> 8
+	y := (inst & emulator.N3_MASK) >> 4
+	n := inst & emulator.N4_MASK
+	nn := inst & (emulator.N3_MASK | emulator.N4_MASK)
+	i := m.I()
+
+	switch inst & emulator.N1_MASK {
+	case emulator.SEQ_VX_NN, emulator.SNE_VX_NN:
+		reads = append(reads, reg(x))
+	case emulator.SEQ_VX_VY:
+		switch n {
+		case 0:
+			reads = append(reads, reg(x), reg(y))
+		case emulator.SAVE_VX_VY:
+			reads = append(reads, regs(x, y), index)
+			writes = append(writes, mem(i, regs(x, y).hi-regs(x, y).lo+1))
+		case emulator.LOAD_VX_VY:
+			reads = append(reads, index, mem(i, regs(x, y).hi-regs(x, y).lo+1))
+			writes = append(writes, regs(x, y))
+		}
+	case emulator.SNE_VX_VY:
+		reads = append(reads, reg(x), reg(y))
+	case emulator.LD_VX_KK:
+		writes = append(writes, reg(x))
+	case emulator.ADD_VX_KK:
+		reads = append(reads, reg(x))
+		writes = append(writes, reg(x))
+	case emulator.MOD_VX_VY_OPS:
+		reads = append(reads, reg(y))
+		if n != emulator.LD_VX_VY {
+			reads = append(reads, reg(x))
+			writes = append(writes, vf)
+		}
+		writes = append(writes, reg(x))
+	case emulator.LD_I:
+		writes = append(writes, index)
+	case emulator.JMP_V0:
+		reads = append(reads, reg(0), reg(x))
+	case emulator.RND_VX_KK:
+		writes = append(writes, reg(x))
+	case emulator.DRW_VX_VY_N:
+		size := n
+		if n == 0 {
+			size = 32
+		}
+		reads = append(reads, reg(x), reg(y), index, mem(i, size))
+		writes = append(writes, vf)
+	case emulator.VX_KEY_OPS:
+		reads = append(reads, reg(x))
+	case emulator.TIMING_OPS:
+		switch nn {
+		case emulator.LD_VX_DT:
+			reads = append(reads, delay)
+			writes = append(writes, reg(x))
+		case emulator.LD_VX_K:
+			writes = append(writes, reg(x))
+		case emulator.LD_DT_VX:
+			reads = append(reads, reg(x))
+			writes = append(writes, delay)
+		case emulator.LD_ST_VX:
+			reads = append(reads, reg(x))
+			writes = append(writes, sound)
+		case emulator.ADD_I_VX:
+			reads = append(reads, reg(x), index)
+			writes = append(writes, index, vf)
+		case emulator.LD_F_VX, emulator.LD_HF_VX:
+			reads = append(reads, reg(x))
+			writes = append(writes, index)
+		case emulator.LD_B_VX:
+			reads = append(reads, reg(x), index)
+			writes = append(writes, mem(i, 3))
+		case emulator.LD_I_VX:
+			reads = append(reads, regs(0, x), index)
+			writes = append(writes, mem(i, x+1))
+		case emulator.LD_VX_I:
+			reads = append(reads, index, mem(i, x+1))
+			writes = append(writes, regs(0, x))
+		case emulator.LD_R_VX:
+			reads = append(reads, regs(0, x))
+		case emulator.LD_VX_R:
+			writes = append(writes, regs(0, x))
+		case emulator.LD_I_LONG:
+			writes = append(writes, index)
+		case emulator.AUDIO:
+			reads = append(reads, index, mem(i, 16))
+		case emulator.PITCH_VX:
+			reads = append(reads, reg(x))
+		}
+	}
+	return reads, writes
+}
@@ -0,0 +1,638 @@
+// Package debugger implements an interactive debugger for a
+// CHIP-8 machine, driven by commands read line by line.
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bchadwic/chip8/emulator"
+	"github.com/bchadwic/chip8/internal/keypad"
+)
+
+const (
+	// maximum instructions executed by next and out
+	STEP_LIMIT = 1_000_000
+	// instructions shown around pc by dis
+	DIS_BEFORE, DIS_AFTER = 3, 8
+)
+
+type breakpoint struct {
+	id   int
+	text string
+	// matches when inst&mask == value, or
+	// when pc == addr for address breakpoints
+	opcode      bool
+	addr        uint16
+	mask, value uint16
+}
+
+type watchpoint struct {
+	id          int
+	text        string
+	target      access
+	read, write bool
+}
+
+type Debugger struct {
+	m   emulator.Machine
+	in  *bufio.Scanner
+	out io.Writer
+
+	// guards the fields below, hooks are called
+	// from the goroutine running the machine
+	mu          sync.Mutex
+	breakpoints []breakpoint
+	watchpoints []watchpoint
+	ids         int
+	// ignore breaks on the next instruction, so
+	// execution can move past the one it stopped on
+	skip bool
+	// set once a break has paused the machine
+	hit bool
+	// instructions left in the frame being stepped through, a
+	// new frame is started with Tick once they run out
+	left int
+
+	outMu sync.Mutex
+}
+
+// Create attaches a debugger to the machine, commands are
+// read from in and everything is reported to out
+func Create(m emulator.Machine, in io.Reader, out io.Writer) *Debugger {
+	d := &Debugger{
+		m:   m,
+		in:  bufio.NewScanner(in),
+		out: out,
+	}
+	m.SetHook(d)
+	return d
+}
+
+// Run reads and executes commands until quit or the end of input
+func (d *Debugger) Run() error {
+	d.printf("type help for a list of commands\n")
+	if d.m.Paused() {
+		d.where()
+	}
+	d.prompt()
+	for d.in.Scan() {
+		args := strings.Fields(d.in.Text())
+		if len(args) > 0 && d.exec(args) {
+			return nil
+		}
+		d.prompt()
+	}
+	return d.in.Err()
+}
+
+// Break pauses the machine on breakpoints and watchpoints
+func (d *Debugger) Break(m emulator.Machine, inst uint16) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.skip {
+		d.skip = false
+		return false
+	}
+
+	reason := ""
+	pc := m.PC()
+	for _, bp := range d.breakpoints {
+		if (bp.opcode && inst&bp.mask == bp.value) || (!bp.opcode && pc == bp.addr) {
+			reason = fmt.Sprintf("breakpoint #%d, %s", bp.id, bp.text)
+			break
+		}
+	}
+	if reason == "" && len(d.watchpoints) > 0 {
+		reads, writes := accesses(m, inst)
+		for _, wp := range d.watchpoints {
+			if (wp.read && touches(wp.target, reads)) || (wp.write && touches(wp.target, writes)) {
+				reason = fmt.Sprintf("watchpoint #%d, %s", wp.id, wp.text)
+				break
+			}
+		}
+	}
+	if reason == "" {
+		return false
+	}
+	d.hit = true
+	d.printf("\n%s\n", reason)
+	d.where()
+	return true
+}
+
+// Trap reports faults that paused the machine
+func (d *Debugger) Trap(m emulator.Machine, f *emulator.Fault) {
+	d.mu.Lock()
+	d.hit = true
+	d.mu.Unlock()
+	d.printf("\ntrapped: %v\n", f)
+	d.where()
+}
+
+func touches(target access, accesses []access) bool {
+	for _, a := range accesses {
+		if target.overlaps(a) {
+			return true
+		}
+	}
+	return false
+}
+
+var help = `commands:
+  c, continue             resume execution
+  p, pause                pause execution
+  s, step [n]             execute n instructions
+  n, next                 step over a subroutine call
+  o, out                  run until the current subroutine returns
+  b, break <addr>         break when pc reaches addr
+  bo <opcode>             break on opcodes matching a pattern like Dxxx
+  w, watch [r|w|rw] <loc> break on access to v0-vF, i, dt, st, addr or addr-addr
+  l, list                 list breakpoints and watchpoints
+  d, delete <id|all>      delete a breakpoint or watchpoint
+  r, regs                 show registers
+  m, mem <addr> [n]       show n bytes of memory
+  dis [addr]              disassemble around pc or addr
+  set <loc> <value>       set v0-vF, i, pc, sp, dt, st or addr to value
+  h, help                 show this help
+  q, quit                 exit
+`
+
+// exec runs a single command, returning true to quit
+func (d *Debugger) exec(args []string) bool {
+	cmd, args := strings.ToLower(args[0]), args[1:]
+	switch cmd {
+	case "q", "quit", "exit":
+		return true
+	case "h", "help":
+		d.printf("%s", help)
+	case "c", "continue":
+		d.mu.Lock()
+		d.skip = true
+		d.mu.Unlock()
+		d.m.Resume()
+	case "p", "pause":
+		d.m.Pause()
+		d.where()
+	case "b", "break", "bo":
+		d.addBreakpoint(cmd == "bo", args)
+	case "w", "watch":
+		d.addWatchpoint(args)
+	case "l", "list":
+		d.list()
+	case "d", "delete":
+		d.delete(args)
+	default:
+		if !d.m.Paused() {
+			d.printf("the machine is running, pause it first\n")
+			return false
+		}
+		d.paused(cmd, args)
+	}
+	return false
+}
+
+// paused runs commands that need the machine to be paused
+func (d *Debugger) paused(cmd string, args []string) {
+	switch cmd {
+	case "s", "step":
+		n := 1
+		if len(args) > 0 {
+			v, err := strconv.Atoi(args[0])
+			if err != nil || v < 1 {
+				d.printf("invalid count: %s\n", args[0])
+				return
+			}
+			n = v
+		}
+		d.run(n, func() bool { return false })
+		d.where()
+	case "n", "next":
+		pc, sp := d.m.PC(), d.m.SP()
+		if d.opcode(pc)&emulator.N1_MASK != emulator.CALL {
+			d.run(1, func() bool { return false })
+		} else {
+			d.run(STEP_LIMIT, func() bool { return d.m.PC() == pc+2 && d.m.SP() == sp })
+		}
+		d.where()
+	case "o", "out":
+		sp := d.m.SP()
+		if sp == 0 {
+			d.printf("not in a subroutine\n")
+			return
+		}
+		d.run(STEP_LIMIT, func() bool { return d.m.SP() < sp })
+		d.where()
+	case "r", "regs":
+		d.regs()
+	case "m", "mem":
+		d.mem(args)
+	case "dis":
+		addr := d.m.PC()
+		if len(args) > 0 {
+			v, err := parseNumber(args[0])
+			if err != nil {
+				d.printf("%v\n", err)
+				return
+			}
+			addr = v
+		}
+		d.dis(addr)
+	case "set":
+		d.set(args)
+	default:
+		d.printf("unknown command: %s\n", cmd)
+	}
+}
+
+// run steps the paused machine up to n times, stopping early when
+// done returns true, a break is hit, the machine faults, or FX0A
+// waits on a key. Frames are started as they would be when running,
+// so the timers count down and keys are seen while stepping
+func (d *Debugger) run(n int, done func() bool) {
+	d.mu.Lock()
+	d.skip, d.hit = true, false
+	d.mu.Unlock()
+	for i := 0; i < n; i++ {
+		var err error
+		// the frame loop keeps presenting the machine while it is
+		// paused, so each step holds the lock it runs frames under
+		d.m.Do(func() {
+			if d.left <= 0 {
+				d.left = d.m.Tick()
+			}
+			d.left--
+			err = d.m.Step()
+		})
+		if err != nil {
+			d.printf("%v\n", err)
+			return
+		}
+		if d.m.Waiting() {
+			d.printf("waiting for a key, press one and step again\n")
+			return
+		}
+		d.mu.Lock()
+		hit := d.hit
+		d.mu.Unlock()
+		if hit || d.m.Fault() != nil || d.m.Halted() || done() {
+			return
+		}
+	}
+}
+
+func (d *Debugger) addBreakpoint(opcode bool, args []string) {
+	if len(args) != 1 {
+		d.printf("expected a single address or opcode\n")
+		return
+	}
+	bp := breakpoint{opcode: opcode}
+	if opcode {
+		pattern := strings.ToUpper(strings.TrimPrefix(args[0], "0x"))
+		if len(pattern) != 4 {
+			d.printf("opcode patterns are 4 digits, use x for any digit\n")
+			return
+		}
+		for _, c := range pattern {
+			bp.mask <<= 4
+			bp.value <<= 4
+			if c == 'X' || c == '?' || c == '*' {
+				continue
+			}
+			v, err := strconv.ParseUint(string(c), 16, 4)
+			if err != nil {
+				d.printf("invalid opcode pattern: %s\n", args[0])
+				return
+			}
+			bp.mask |= 0xF
+			bp.value |= uint16(v)
+		}
+		bp.text = "opcode " + pattern
+	} else {
+		addr, err := parseNumber(args[0])
+		if err != nil {
+			d.printf("%v\n", err)
+			return
+		}
+		bp.addr = addr
+		bp.text = fmt.Sprintf("pc 0x%03X", addr)
+	}
+
+	d.mu.Lock()
+	d.ids++
+	bp.id = d.ids
+	d.breakpoints = append(d.breakpoints, bp)
+	d.mu.Unlock()
+	d.printf("breakpoint #%d, %s\n", bp.id, bp.text)
+}
+
+func (d *Debugger) addWatchpoint(args []string) {
+	wp := watchpoint{write: true}
+	if len(args) == 2 {
+		switch strings.ToLower(args[0]) {
+		case "r":
+			wp.read, wp.write = true, false
+		case "w":
+		case "rw":
+			wp.read = true
+		default:
+			d.printf("watch mode must be r, w or rw\n")
+			return
+		}
+		args = args[1:]
+	}
+	if len(args) != 1 {
+		d.printf("expected a location to watch\n")
+		return
+	}
+	target, err := parseLocation(args[0])
+	if err != nil {
+		d.printf("%v\n", err)
+		return
+	}
+	wp.target = target
+	mode := map[[2]bool]string{{true, false}: "read", {false, true}: "write", {true, true}: "read/write"}
+	wp.text = fmt.Sprintf("%s %s", mode[[2]bool{wp.read, wp.write}], strings.ToLower(args[0]))
+
+	d.mu.Lock()
+	d.ids++
+	wp.id = d.ids
+	d.watchpoints = append(d.watchpoints, wp)
+	d.mu.Unlock()
+	d.printf("watchpoint #%d, %s\n", wp.id, wp.text)
+}
+
+func (d *Debugger) list() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.breakpoints)+len(d.watchpoints) == 0 {
+		d.printf("no breakpoints or watchpoints\n")
+	}
+	for _, bp := range d.breakpoints {
+		d.printf("#%d break %s\n", bp.id, bp.text)
+	}
+	for _, wp := range d.watchpoints {
+		d.printf("#%d watch %s\n", wp.id, wp.text)
+	}
+}
+
+func (d *Debugger) delete(args []string) {
+	if len(args) != 1 {
+		d.printf("expected an id or all\n")
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if args[0] == "all" {
+		d.breakpoints, d.watchpoints = nil, nil
+		return
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+	if err != nil {
+		d.printf("invalid id: %s\n", args[0])
+		return
+	}
+	for i, bp := range d.breakpoints {
+		if bp.id == id {
+			d.breakpoints = append(d.breakpoints[:i], d.breakpoints[i+1:]...)
+			return
+		}
+	}
+	for i, wp := range d.watchpoints {
+		if wp.id == id {
+			d.watchpoints = append(d.watchpoints[:i], d.watchpoints[i+1:]...)
+			return
+		}
+	}
+	d.printf("no breakpoint or watchpoint #%d\n", id)
+}
+
+func (d *Debugger) regs() {
+	var b strings.Builder
+	for x, v := range d.m.Registers() {
+		fmt.Fprintf(&b, "V%X=%02X", x, v)
+		if x%8 == 7 {
+			b.WriteString("\n")
+		} else {
+			b.WriteString(" ")
+		}
+	}
+	fmt.Fprintf(&b, "PC=0x%03X I=0x%03X SP=%d DT=%02X ST=%02X\n", d.m.PC(), d.m.I(), d.m.SP(), d.m.DT(), d.m.ST())
+	stack := d.m.Stack()[:d.m.SP()]
+	if len(stack) > 0 {
+		b.WriteString("stack:")
+		for _, addr := range stack {
+			fmt.Fprintf(&b, " 0x%03X", addr)
+		}
+		b.WriteString("\n")
+	}
+	// keys down for the current frame
+	if state := d.m.Keypad().State(); state != 0 {
+		b.WriteString("keys:")
+		for key := 0; key < keypad.KEYS; key++ {
+			if state&(1<<key) != 0 {
+				fmt.Fprintf(&b, " %X", key)
+			}
+		}
+		b.WriteString("\n")
+	}
+	d.printf("%s", b.String())
+}
+
+func (d *Debugger) mem(args []string) {
+	if len(args) == 0 {
+		d.printf("expected an address\n")
+		return
+	}
+	addr, err := parseNumber(args[0])
+	if err != nil {
+		d.printf("%v\n", err)
+		return
+	}
+	n := 16
+	if len(args) > 1 {
+		n, err = strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			d.printf("invalid count: %s\n", args[1])
+			return
+		}
+	}
+	var b strings.Builder
+	for o := 0; o < n && int(addr)+o < d.m.MemorySize(); o++ {
+		if o%16 == 0 {
+			if o > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "0x%03X:", int(addr)+o)
+		}
+		fmt.Fprintf(&b, " %02X", d.m.Memory(addr+uint16(o)))
+	}
+	b.WriteString("\n")
+	d.printf("%s", b.String())
+}
+
+// dis disassembles the instructions around addr
+func (d *Debugger) dis(addr uint16) {
+	start := int(addr) - 2*DIS_BEFORE
+	if start < 0 {
+		start = int(addr) % 2
+	}
+	d.mu.Lock()
+	breaks := map[int]bool{}
+	for _, bp := range d.breakpoints {
+		if !bp.opcode {
+			breaks[int(bp.addr)] = true
+		}
+	}
+	d.mu.Unlock()
+
+	var b strings.Builder
+	pc := int(d.m.PC())
+	for at, i := start, 0; at < d.m.MemorySize() && i < DIS_BEFORE+DIS_AFTER; i++ {
+		code := d.code(uint16(at))
+		mnemonic, size := emulator.Disassemble(code)
+		marker := "  "
+		if at == pc {
+			marker = "=>"
+		}
+		bp := " "
+		if breaks[at] {
+			bp = "*"
+		}
+		fmt.Fprintf(&b, "%s%s0x%03X: % X  %s\n", marker, bp, at, code[:size], mnemonic)
+		at += size
+	}
+	d.printf("%s", b.String())
+}
+
+func (d *Debugger) set(args []string) {
+	if len(args) != 2 {
+		d.printf("expected a location and a value\n")
+		return
+	}
+	v, err := parseNumber(args[1])
+	if err != nil {
+		d.printf("%v\n", err)
+		return
+	}
+	switch loc := strings.ToLower(args[0]); loc {
+	case "pc":
+		d.m.SetPC(v)
+	case "sp":
+		d.m.SetSP(uint8(v))
+	default:
+		target, err := parseLocation(loc)
+		if err != nil {
+			d.printf("%v\n", err)
+			return
+		}
+		switch target.kind {
+		case REGISTER:
+			d.m.SetRegister(uint8(target.lo), uint8(v))
+		case INDEX:
+			d.m.SetI(v)
+		case DELAY:
+			d.m.SetDT(uint8(v))
+		case SOUND:
+			d.m.SetST(uint8(v))
+		case MEMORY:
+			for addr := int(target.lo); addr <= int(target.hi); addr++ {
+				d.m.SetMemory(uint16(addr), uint8(v))
+			}
+		}
+	}
+}
+
+// where prints the instruction at pc
+func (d *Debugger) where() {
+	pc := d.m.PC()
+	if int(pc) >= d.m.MemorySize() {
+		d.printf("pc 0x%03X is out of memory\n", pc)
+		return
+	}
+	code := d.code(pc)
+	mnemonic, size := emulator.Disassemble(code)
+	d.printf("=> 0x%03X: % X  %s\n", pc, code[:size], mnemonic)
+}
+
+// code returns up to four bytes of memory starting at addr
+func (d *Debugger) code(addr uint16) []uint8 {
+	var code []uint8
+	for o := 0; o < 4 && int(addr)+o < d.m.MemorySize(); o++ {
+		code = append(code, d.m.Memory(addr+uint16(o)))
+	}
+	return code
+}
+
+// opcode returns the two byte instruction at addr
+func (d *Debugger) opcode(addr uint16) uint16 {
+	code := d.code(addr)
+	if len(code) < 2 {
+		return 0
+	}
+	return uint16(code[0])<<8 | uint16(code[1])
+}
+
+func (d *Debugger) prompt() {
+	d.printf("(chip8) ")
+}
+
+func (d *Debugger) printf(format string, args ...any) {
+	d.outMu.Lock()
+	defer d.outMu.Unlock()
+	fmt.Fprintf(d.out, format, args...)
+}
+
+// parseNumber parses hex prefixed with 0x or $, or decimal
+func parseNumber(s string) (uint16, error) {
+	base := 10
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s, base = s[2:], 16
+	} else if strings.HasPrefix(s, "$") {
+		s, base = s[1:], 16
+	}
+	v, err := strconv.ParseUint(s, base, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number: %s", s)
+	}
+	return uint16(v), nil
+}
+
+// parseLocation parses a register, timer, or memory address or range
+func parseLocation(s string) (access, error) {
+	s = strings.ToLower(s)
+	switch s {
+	case "i":
+		return index, nil
+	case "dt":
+		return delay, nil
+	case "st":
+		return sound, nil
+	}
+	if len(s) == 2 && s[0] == 'v' {
+		x, err := strconv.ParseUint(s[1:], 16, 4)
+		if err != nil {
+			return access{}, fmt.Errorf("invalid register: %s", s)
+		}
+		return reg(uint16(x)), nil
+	}
+	lo, hi, isRange := strings.Cut(s, "-")
+	from, err := parseNumber(lo)
+	if err != nil {
+		return access{}, err
+	}
+	to := from
+	if isRange {
+		if to, err = parseNumber(hi); err != nil {
+			return access{}, err
+		}
+		if to < from {
+			return access{}, fmt.Errorf("invalid range: %s", s)
+		}
+	}
+	return access{kind: MEMORY, lo: from, hi: to}, nil
+}
@@ -0,0 +1,174 @@
+package debugger
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bchadwic/chip8/emulator"
+	"github.com/stretchr/testify/assert"
+)
+
+var testROM = []uint8{
+	0x60, 0x05, // 0x200: LD V0, 0x05
+	0x22, 0x08, // 0x202: CALL 0x208
+	0x71, 0x01, // 0x204: ADD V1, 0x01
+	0x12, 0x04, // 0x206: JMP 0x204
+	0x62, 0x03, // 0x208: LD V2, 0x03
+	0x00, 0xEE, // 0x20A: RET
+}
+
+func testDebugger(t *testing.T, script string) (emulator.Machine, *bytes.Buffer) {
+	m := emulator.Create(&emulator.EmulatorSettings{Headless: true})
+	assert.NoError(t, m.LoadROM(testROM))
+	out := &bytes.Buffer{}
+	d := Create(m, strings.NewReader(script), out)
+	m.Pause()
+	assert.NoError(t, d.Run())
+	return m, out
+}
+
+func Test_Step(t *testing.T) {
+	m, _ := testDebugger(t, "s\ns 2\n")
+	assert.Equal(t, uint16(0x20A), m.PC())
+	assert.Equal(t, uint8(1), m.SP())
+}
+
+func Test_Next(t *testing.T) {
+	m, _ := testDebugger(t, "s\nn\n")
+	assert.Equal(t, uint16(0x204), m.PC())
+	assert.Equal(t, uint8(0x03), m.Register(2))
+	assert.Equal(t, uint8(0), m.SP())
+}
+
+func Test_Out(t *testing.T) {
+	m, _ := testDebugger(t, "s 2\no\n")
+	assert.Equal(t, uint16(0x204), m.PC())
+	assert.Equal(t, uint8(0), m.SP())
+}
+
+func Test_Breakpoint(t *testing.T) {
+	m, out := testDebugger(t, "b 0x208\nc\n")
+	assert.NoError(t, m.Run(1))
+	assert.True(t, m.Paused())
+	assert.Equal(t, uint16(0x208), m.PC())
+	assert.Contains(t, out.String(), "breakpoint #1, pc 0x208")
+}
+
+func Test_Breakpoint_opcode(t *testing.T) {
+	m, _ := testDebugger(t, "bo 00ee\nc\n")
+	assert.NoError(t, m.Run(1))
+	assert.True(t, m.Paused())
+	assert.Equal(t, uint16(0x20A), m.PC())
+}
+
+func Test_Watchpoint(t *testing.T) {
+	m, out := testDebugger(t, "w v1\nc\n")
+	assert.NoError(t, m.Run(1))
+	assert.True(t, m.Paused())
+	assert.Equal(t, uint16(0x204), m.PC())
+	assert.Equal(t, uint8(0), m.Register(1))
+	assert.Contains(t, out.String(), "watchpoint #1, write v1")
+}
+
+func Test_Watchpoint_read(t *testing.T) {
+	m, _ := testDebugger(t, "w r v1\nw v0\nd 2\nc\n")
+	assert.NoError(t, m.Run(1))
+	assert.True(t, m.Paused())
+	assert.Equal(t, uint16(0x204), m.PC())
+}
+
+func Test_Set(t *testing.T) {
+	m, _ := testDebugger(t, "set v3 0x10\nset i 0x300\nset 0x300-0x301 7\nset pc 0x204\n")
+	assert.Equal(t, uint8(0x10), m.Register(3))
+	assert.Equal(t, uint16(0x300), m.I())
+	assert.Equal(t, uint8(7), m.Memory(0x301))
+	assert.Equal(t, uint16(0x204), m.PC())
+}
+
+func Test_Running(t *testing.T) {
+	m, out := testDebugger(t, "c\ns\n")
+	assert.False(t, m.Paused())
+	assert.Equal(t, uint16(0x200), m.PC())
+	assert.Contains(t, out.String(), "pause it first")
+}
+
+func Test_accesses(t *testing.T) {
+	m := emulator.Create(&emulator.EmulatorSettings{Headless: true})
+	m.SetI(0x300)
+
+	reads, writes := accesses(m, 0x8124)
+	assert.Equal(t, []access{reg(2), reg(1)}, reads)
+	assert.Equal(t, []access{vf, reg(1)}, writes)
+
+	reads, writes = accesses(m, 0xF355)
+	assert.Equal(t, []access{regs(0, 3), index}, reads)
+	assert.Equal(t, []access{mem(0x300, 4)}, writes)
+}
+
+func Test_parseLocation(t *testing.T) {
+	loc, err := parseLocation("VA")
+	assert.NoError(t, err)
+	assert.Equal(t, reg(0xA), loc)
+
+	loc, err = parseLocation("0x300-0x30F")
+	assert.NoError(t, err)
+	assert.Equal(t, mem(0x300, 16), loc)
+
+	_, err = parseLocation("0x30F-0x300")
+	assert.Error(t, err)
+}
+
+func Test_Regs_keys(t *testing.T) {
+	m := emulator.Create(&emulator.EmulatorSettings{Headless: true})
+	assert.NoError(t, m.LoadROM(testROM))
+	m.Keypad().Restore(0x8002)
+	out := &bytes.Buffer{}
+	m.Pause()
+	assert.NoError(t, Create(m, strings.NewReader("r\n"), out).Run())
+	assert.Contains(t, out.String(), "keys: 1 F\n")
+}
+
+func Test_Step_keys(t *testing.T) {
+	m := emulator.Create(&emulator.EmulatorSettings{Headless: true})
+	assert.NoError(t, m.LoadROM([]uint8{
+		0xE0, 0x9E, // SKP V0
+		0x12, 0x00, // JMP 0x200
+		0xF1, 0x0A, // LD V1, K
+		0x12, 0x06, // JMP 0x206
+	}))
+	m.Keypad().Press(0x0)
+	m.Keypad().Release(0x0)
+	out := &bytes.Buffer{}
+	m.Pause()
+	// the key is latched when stepping starts a frame, then FX0A
+	// stops next rather than spinning until the step limit
+	assert.NoError(t, Create(m, strings.NewReader("s\nn\n"), out).Run())
+	assert.Equal(t, uint16(0x204), m.PC())
+	assert.True(t, m.Waiting())
+	assert.Contains(t, out.String(), "waiting for a key")
+}
+
+func Test_Step_running(t *testing.T) {
+	m := emulator.Create(&emulator.EmulatorSettings{Headless: true, Platform: emulator.PLATFORM_SCHIP})
+	assert.NoError(t, m.LoadROM([]uint8{
+		0x70, 0x01, // 0x200: ADD V0, 0x01
+		0x30, 0x00, // 0x202: SEQ V0, 0x00
+		0x12, 0x00, // 0x204: JMP 0x200
+		0x71, 0x01, // 0x206: ADD V1, 0x01
+		0x31, 0x00, // 0x208: SEQ V1, 0x00
+		0x12, 0x00, // 0x20A: JMP 0x200
+		0x00, 0xFD, // 0x20C: EXIT
+	}))
+	m.Pause()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- m.StartContext(ctx) }()
+	// steps take the lock the running machine takes each frame, the
+	// machine exits from a step several frames in and Start returns
+	assert.NoError(t, Create(m, strings.NewReader("s 300000\n"), &bytes.Buffer{}).Run())
+	assert.True(t, m.Halted())
+	cancel()
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,172 @@
+// Package disasm turns CHIP-8 ROMs into readable listings, following
+// control flow from the entry point to separate code from data.
+package disasm
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bchadwic/chip8/emulator"
+)
+
+// bytes of data shown on a single line
+const DATA_WIDTH = 8
+
+// Line is a single instruction or run of data in a listing
+type Line struct {
+	Addr uint16
+	// label of Addr when it is the target of a jump or call
+	Label string
+	Bytes []uint8
+	Text  string
+	Code  bool
+}
+
+// Disassemble lists a ROM loaded at ROM_ADDR, bytes reached by
+// following control flow from ROM_ADDR are shown as instructions,
+// everything else as data. Jump and call targets are labeled.
+func Disassemble(rom []uint8) []Line {
+	code, labels := trace(rom)
+
+	var lines []Line
+	for at := 0; at < len(rom); {
+		addr := uint16(emulator.ROM_ADDR + at)
+		if code[at] {
+			text, size := emulator.Disassemble(rom[at:])
+			if target, ok := target(rom[at:]); ok && labels[target] != "" {
+				text = strings.Replace(text, fmt.Sprintf("0x%03X", target), labels[target], 1)
+			}
+			lines = append(lines, Line{Addr: addr, Label: labels[addr], Bytes: rom[at : at+size], Text: text, Code: true})
+			at += size
+			continue
+		}
+
+		// data runs until the next code or label, or the line is full
+		end := at + 1
+		for end < len(rom) && end-at < DATA_WIDTH && !code[end] && labels[uint16(emulator.ROM_ADDR+end)] == "" {
+			end++
+		}
+		data := make([]string, end-at)
+		for i, b := range rom[at:end] {
+			data[i] = fmt.Sprintf("0x%02X", b)
+		}
+		lines = append(lines, Line{Addr: addr, Label: labels[addr], Bytes: rom[at:end], Text: "DB " + strings.Join(data, ", ")})
+		at = end
+	}
+	return lines
+}
+
+// Fprint writes a listing of addresses, raw bytes and mnemonics
+func Fprint(w io.Writer, lines []Line) error {
+	for _, l := range lines {
+		if l.Label != "" {
+			if _, err := fmt.Fprintf(w, "%s:\n", l.Label); err != nil {
+				return err
+			}
+		}
+		raw := fmt.Sprintf("% X", l.Bytes)
+		if _, err := fmt.Fprintf(w, "0x%03X  %-24s %s\n", l.Addr, raw, l.Text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trace follows every path through the program from ROM_ADDR,
+// marking the bytes that are executed as code and naming the
+// addresses that are jumped to or called
+func trace(rom []uint8) ([]bool, map[uint16]string) {
+	code := make([]bool, len(rom))
+	starts := make([]bool, len(rom))
+	labels := map[uint16]string{}
+	calls := map[uint16]bool{}
+	jumps := map[uint16]bool{}
+
+	pending := []int{0}
+	for len(pending) > 0 {
+		at := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		for at >= 0 && at+1 < len(rom) && !code[at] {
+			text, size := emulator.Disassemble(rom[at:])
+			if strings.HasPrefix(text, "DW") {
+				break
+			}
+			for i := 0; i < size; i++ {
+				code[at+i] = true
+			}
+			starts[at] = true
+
+			inst := uint16(rom[at])<<8 | uint16(rom[at+1])
+			addr := inst & (emulator.N2_MASK | emulator.N3_MASK | emulator.N4_MASK)
+			next := at + size
+			switch {
+			case inst == emulator.RET || inst == emulator.EXIT:
+				next = -1
+			case inst&emulator.N1_MASK == emulator.JMP:
+				jumps[addr] = true
+				next = int(addr) - emulator.ROM_ADDR
+			case inst&emulator.N1_MASK == emulator.CALL:
+				calls[addr] = true
+				pending = append(pending, int(addr)-emulator.ROM_ADDR)
+			case inst&emulator.N1_MASK == emulator.JMP_V0:
+				// the offset in v0 is unknown, so only the base is followed
+				jumps[addr] = true
+				pending = append(pending, int(addr)-emulator.ROM_ADDR)
+				next = -1
+			case skips(inst):
+				// skipping over a long instruction moves four bytes
+				skip := next + 2
+				if next+1 < len(rom) {
+					_, n := emulator.Disassemble(rom[next:])
+					skip = next + n
+				}
+				pending = append(pending, skip)
+			}
+			at = next
+		}
+	}
+
+	for addr := range jumps {
+		labels[addr] = fmt.Sprintf("loc_%03X", addr)
+	}
+	for addr := range calls {
+		labels[addr] = fmt.Sprintf("sub_%03X", addr)
+	}
+	// targets outside of the rom, or in the middle
+	// of another instruction, are left as addresses
+	for addr := range labels {
+		at := int(addr) - emulator.ROM_ADDR
+		if at < 0 || at >= len(rom) || (code[at] && !starts[at]) {
+			delete(labels, addr)
+		}
+	}
+	return code, labels
+}
+
+// target returns the address a jump or call at the start of code goes to
+func target(code []uint8) (uint16, bool) {
+	if len(code) < 2 {
+		return 0, false
+	}
+	inst := uint16(code[0])<<8 | uint16(code[1])
+	switch inst & emulator.N1_MASK {
+	case emulator.JMP, emulator.CALL, emulator.JMP_V0:
+		return inst & (emulator.N2_MASK | emulator.N3_MASK | emulator.N4_MASK), true
+	}
+	return 0, false
+}
+
+// skips reports whether inst conditionally skips the next instruction
+func skips(inst uint16) bool {
+	switch inst & emulator.N1_MASK {
+	case emulator.SEQ_VX_NN, emulator.SNE_VX_NN:
+		return true
+	case emulator.SEQ_VX_VY, emulator.SNE_VX_VY:
+		return inst&emulator.N4_MASK == 0
+	case emulator.VX_KEY_OPS:
+		nn := inst & (emulator.N3_MASK | emulator.N4_MASK)
+		return nn == emulator.SEQ_VX_KEY_PR || nn == emulator.SNE_VX_KEY_PR
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package disasm
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testROM = []uint8{
+	0xA2, 0x0C, // 0x200: LD I, 0x20C
+	0x22, 0x08, // 0x202: CALL 0x208
+	0x30, 0x01, // 0x204: SEQ V0, 0x01
+	0x12, 0x04, // 0x206: JMP 0x204
+	0xD0, 0x11, // 0x208: DRW V0, V1, 1
+	0x00, 0xEE, // 0x20A: RET
+	0xFF, 0x81, // 0x20C: sprite
+	0x42,
+}
+
+func Test_Disassemble(t *testing.T) {
+	lines := Disassemble(testROM)
+	assert.Equal(t, []Line{
+		{Addr: 0x200, Bytes: []uint8{0xA2, 0x0C}, Text: "LD I, 0x20C", Code: true},
+		{Addr: 0x202, Bytes: []uint8{0x22, 0x08}, Text: "CALL sub_208", Code: true},
+		{Addr: 0x204, Label: "loc_204", Bytes: []uint8{0x30, 0x01}, Text: "SEQ V0, 0x01", Code: true},
+		{Addr: 0x206, Bytes: []uint8{0x12, 0x04}, Text: "JMP loc_204", Code: true},
+		{Addr: 0x208, Label: "sub_208", Bytes: []uint8{0xD0, 0x11}, Text: "DRW V0, V1, 1", Code: true},
+		{Addr: 0x20A, Bytes: []uint8{0x00, 0xEE}, Text: "RET", Code: true},
+		{Addr: 0x20C, Bytes: []uint8{0xFF, 0x81, 0x42}, Text: "DB 0xFF, 0x81, 0x42"},
+	}, lines)
+}
+
+func Test_Disassemble_skipLong(t *testing.T) {
+	lines := Disassemble([]uint8{
+		0x30, 0x01, // 0x200: SEQ V0, 0x01
+		0xF0, 0x00, 0x02, 0x08, // 0x202: LD I, LONG 0x0208
+		0x12, 0x06, // 0x206: JMP 0x206
+	})
+	assert.Len(t, lines, 3)
+	assert.Equal(t, "loc_206", lines[2].Label)
+	assert.True(t, lines[2].Code)
+}
+
+func Test_Disassemble_unknown(t *testing.T) {
+	// execution stops at an unknown opcode, which is left as data
+	lines := Disassemble([]uint8{0x60, 0x01, 0x81, 0x28})
+	assert.Len(t, lines, 2)
+	assert.True(t, lines[0].Code)
+	assert.Equal(t, "DB 0x81, 0x28", lines[1].Text)
+}
+
+func Test_Disassemble_overlap(t *testing.T) {
+	// the jump to 0x205 lands inside the instruction at 0x204,
+	// leaving a single byte of code at the end of the rom
+	lines := Disassemble([]uint8{0x30, 0x80, 0x12, 0x05, 0xB9, 0x22, 0x8D})
+	assert.Len(t, lines, 4)
+	assert.Equal(t, Line{Addr: 0x206, Bytes: []uint8{0x8D}, Text: "DB 0x8D", Code: true}, lines[3])
+}
+
+func Test_Fprint(t *testing.T) {
+	out := &bytes.Buffer{}
+	assert.NoError(t, Fprint(out, Disassemble(testROM[:8])))
+	assert.Equal(t, ""+
+		"0x200  A2 0C                    LD I, 0x20C\n"+
+		"0x202  22 08                    CALL 0x208\n"+
+		"loc_204:\n"+
+		"0x204  30 01                    SEQ V0, 0x01\n"+
+		"0x206  12 04                    JMP loc_204\n", out.String())
+}
@@ -0,0 +1,140 @@
+package emulator
+
+import "fmt"
+
+// Disassemble decodes the instruction at the start of code into its
+// mnemonic, returning the mnemonic and the size of the instruction in
+// bytes. Instructions from every platform are decoded, anything else
+// is shown as a data word.
+func Disassemble(code []uint8) (string, int) {
+	if len(code) < 2 {
+		return fmt.Sprintf("DB 0x%02X", code[0]), 1
+	}
+	inst := uint16(code[0])<<8 | uint16(code[1])
+	n1 := inst & N1_MASK
+	n4 := inst & N4_MASK
+	addr := inst & (N2_MASK | N3_MASK | N4_MASK)
+	x := (inst & N2_MASK) >> 8
+	y := (inst & N3_MASK) >> 4
+	nn := inst & (N3_MASK | N4_MASK)
+
+	switch n1 {
+	case CLS_OR_RET:
+		switch {
+		case inst == CLS:
+			return "CLS", 2
+		case inst == RET:
+			return "RET", 2
+		case inst&^N4_MASK == SCD_N:
+			return fmt.Sprintf("SCD %d", n4), 2
+		case inst&^N4_MASK == SCU_N:
+			return fmt.Sprintf("SCU %d", n4), 2
+		case inst == SCR:
+			return "SCR", 2
+		case inst == SCL:
+			return "SCL", 2
+		case inst == EXIT:
+			return "EXIT", 2
+		case inst == LOW:
+			return "LOW", 2
+		case inst == HIGH:
+			return "HIGH", 2
+		}
+	case JMP:
+		return fmt.Sprintf("JMP 0x%03X", addr), 2
+	case CALL:
+		return fmt.Sprintf("CALL 0x%03X", addr), 2
+	case SEQ_VX_NN:
+		return fmt.Sprintf("SEQ V%X, 0x%02X", x, nn), 2
+	case SNE_VX_NN:
+		return fmt.Sprintf("SNE V%X, 0x%02X", x, nn), 2
+	case SEQ_VX_VY:
+		switch n4 {
+		case 0:
+			return fmt.Sprintf("SEQ V%X, V%X", x, y), 2
+		case SAVE_VX_VY:
+			return fmt.Sprintf("SAVE V%X, V%X", x, y), 2
+		case LOAD_VX_VY:
+			return fmt.Sprintf("LOAD V%X, V%X", x, y), 2
+		}
+	case LD_VX_KK:
+		return fmt.Sprintf("LD V%X, 0x%02X", x, nn), 2
+	case ADD_VX_KK:
+		return fmt.Sprintf("ADD V%X, 0x%02X", x, nn), 2
+	case MOD_VX_VY_OPS:
+		if op, ok := modOps[n4]; ok {
+			return fmt.Sprintf("%s V%X, V%X", op, x, y), 2
+		}
+	case SNE_VX_VY:
+		if n4 == 0 {
+			return fmt.Sprintf("SNE V%X, V%X", x, y), 2
+		}
+	case LD_I:
+		return fmt.Sprintf("LD I, 0x%03X", addr), 2
+	case JMP_V0:
+		return fmt.Sprintf("JMP V0, 0x%03X", addr), 2
+	case RND_VX_KK:
+		return fmt.Sprintf("RND V%X, 0x%02X", x, nn), 2
+	case DRW_VX_VY_N:
+		return fmt.Sprintf("DRW V%X, V%X, %d", x, y, n4), 2
+	case VX_KEY_OPS:
+		switch nn {
+		case SEQ_VX_KEY_PR:
+			return fmt.Sprintf("SEQ V%X, K", x), 2
+		case SNE_VX_KEY_PR:
+			return fmt.Sprintf("SNE V%X, K", x), 2
+		}
+	case TIMING_OPS:
+		switch nn {
+		case LD_VX_DT:
+			return fmt.Sprintf("LD V%X, DT", x), 2
+		case LD_VX_K:
+			return fmt.Sprintf("LD V%X, K", x), 2
+		case LD_DT_VX:
+			return fmt.Sprintf("LD DT, V%X", x), 2
+		case LD_ST_VX:
+			return fmt.Sprintf("LD ST, V%X", x), 2
+		case ADD_I_VX:
+			return fmt.Sprintf("ADD I, V%X", x), 2
+		case LD_F_VX:
+			return fmt.Sprintf("LD F, V%X", x), 2
+		case LD_B_VX:
+			return fmt.Sprintf("LD B, V%X", x), 2
+		case LD_I_VX:
+			return fmt.Sprintf("LD [I], V%X", x), 2
+		case LD_VX_I:
+			return fmt.Sprintf("LD V%X, [I]", x), 2
+		case LD_HF_VX:
+			return fmt.Sprintf("LD HF, V%X", x), 2
+		case LD_R_VX:
+			return fmt.Sprintf("LD R, V%X", x), 2
+		case LD_VX_R:
+			return fmt.Sprintf("LD V%X, R", x), 2
+		case LD_I_LONG:
+			if x == 0 && len(code) >= 4 {
+				return fmt.Sprintf("LD I, LONG 0x%04X", uint16(code[2])<<8|uint16(code[3])), 4
+			}
+		case PLANE_N:
+			return fmt.Sprintf("PLANE %d", x), 2
+		case AUDIO:
+			if x == 0 {
+				return "AUDIO", 2
+			}
+		case PITCH_VX:
+			return fmt.Sprintf("PITCH V%X", x), 2
+		}
+	}
+	return fmt.Sprintf("DW 0x%04X", inst), 2
+}
+
+var modOps = map[uint16]string{
+	LD_VX_VY:   "LD",
+	OR_VX_VY:   "OR",
+	AND_VX_VY:  "AND",
+	XOR_VX_VY:  "XOR",
+	ADD_VX_VY:  "ADD",
+	SUB_VX_VY:  "SUB",
+	SHR_VX_VY:  "SHR",
+	SUBN_VX_VY: "SUBN",
+	SHL_VX_VY:  "SHL",
+}
@@ -0,0 +1,32 @@
+package emulator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Disassemble(t *testing.T) {
+	cases := []struct {
+		code     []uint8
+		mnemonic string
+		size     int
+	}{
+		{[]uint8{0x00, 0xE0}, "CLS", 2},
+		{[]uint8{0x12, 0x28}, "JMP 0x228", 2},
+		{[]uint8{0x6A, 0x02}, "LD VA, 0x02", 2},
+		{[]uint8{0x8A, 0xB4}, "ADD VA, VB", 2},
+		{[]uint8{0xD0, 0x15}, "DRW V0, V1, 5", 2},
+		{[]uint8{0xE3, 0x9E}, "SEQ V3, K", 2},
+		{[]uint8{0xF2, 0x65}, "LD V2, [I]", 2},
+		{[]uint8{0x00, 0xC4}, "SCD 4", 2},
+		{[]uint8{0xF0, 0x00, 0x12, 0x34}, "LD I, LONG 0x1234", 4},
+		{[]uint8{0x81, 0x28}, "DW 0x8128", 2},
+		{[]uint8{0xAB}, "DB 0xAB", 1},
+	}
+	for _, c := range cases {
+		mnemonic, size := Disassemble(c.code)
+		assert.Equal(t, c.mnemonic, mnemonic)
+		assert.Equal(t, c.size, size)
+	}
+}
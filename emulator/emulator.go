@@ -1,16 +1,22 @@
 package emulator
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"log"
-	"math/rand"
+	"math/bits"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bchadwic/chip8/internal/display"
 	"github.com/bchadwic/chip8/internal/display/emit"
-	"github.com/bchadwic/chip8/internal/drivers"
+	"github.com/bchadwic/chip8/internal/frontend"
+	"github.com/bchadwic/chip8/internal/hotkey"
 	"github.com/bchadwic/chip8/internal/keypad"
 	"github.com/bchadwic/chip8/internal/speaker"
+	"github.com/bchadwic/chip8/internal/video"
 )
 
 const (
@@ -18,10 +24,31 @@ const (
 	MEM_SIZE   = 4096
 	STACK_SIZE = 16
 
+	// xo-chip extends memory to 64k
+	XO_MEM_SIZE = 65536
+
 	ROWS, COLS = 32, 64
 
-	FONT_ADDR = 0x050
-	ROM_ADDR  = 0x200
+	// super-chip high resolution mode
+	HIRES_ROWS, HIRES_COLS = 64, 128
+
+	// super-chip persistent flag registers, xo-chip has one per register
+	RPL_FLAGS    = 8
+	XO_RPL_FLAGS = 16
+
+	FONT_ADDR     = 0x050
+	BIG_FONT_ADDR = 0x0A0
+	ROM_ADDR      = 0x200
+
+	// delay and sound timers always count down at 60hz,
+	// a frame is the time between two timer ticks
+	TIMER_HZ    = 60
+	DEFAULT_IPS = 700
+
+	// hotkeys waiting to be handled, more are dropped
+	HOTKEY_QUEUE = 8
+	// frames rewinding continues for after the rewind key is last seen
+	REWIND_HOLD = 2
 
 	// niblet masks
 	N1_MASK = 0xF000
@@ -34,6 +61,13 @@ const (
 	// instructions
 	CLS           = 0x00E0 // clear screen
 	RET           = 0x00EE // return from subroutine
+	SCD_N         = 0x00C0 // scroll display down n pixels
+	SCU_N         = 0x00D0 // scroll display up n pixels
+	SCR           = 0x00FB // scroll display right 4 pixels
+	SCL           = 0x00FC // scroll display left 4 pixels
+	EXIT          = 0x00FD // exit the interpreter
+	LOW           = 0x00FE // switch to low resolution
+	HIGH          = 0x00FF // switch to high resolution
 	JMP           = 0x1000 // jump pc to address
 	CALL          = 0x2000 // call subroutine
 	SEQ_VX_NN     = 0x3000 // skip if vx eq nn
@@ -50,6 +84,10 @@ const (
 	VX_KEY_OPS    = 0xE000 // series of skip instructions for key presses
 	TIMING_OPS    = 0xF000 // series of timing instructions
 
+	// sub instructions under SEQ_VX_VY
+	SAVE_VX_VY = 0x0002 // store vx-vy to memory starting at i
+	LOAD_VX_VY = 0x0003 // load vx-vy from memory starting at i
+
 	// sub instructions under MOD_VX_VY_OPS
 	LD_VX_VY   = 0x0000 // store vx in vy
 	OR_VX_VY   = 0x0001 // bitwise vx or vy
@@ -75,6 +113,17 @@ const (
 	LD_B_VX  = 0x0033 // i, i+1, and i+2 represent BCD of vx
 	LD_I_VX  = 0x0055 // load memory i-n with the values stored in v0-vx
 	LD_VX_I  = 0x0065 // load v0-vx with values stored in memory i-n
+
+	// super-chip sub instructions under TIMING_OPS
+	LD_HF_VX = 0x0030 // set i to large sprite stored in vx
+	LD_R_VX  = 0x0075 // store v0-vx in the rpl flags
+	LD_VX_R  = 0x0085 // load v0-vx from the rpl flags
+
+	// xo-chip sub instructions under TIMING_OPS
+	LD_I_LONG = 0x0000 // load i with the 16 bit address that follows
+	PLANE_N   = 0x0001 // select the planes drawn to
+	AUDIO     = 0x0002 // load the audio pattern from memory starting at i
+	PITCH_VX  = 0x003A // set the audio pitch to vx
 )
 
 var fonts []uint8 = []uint8{
@@ -96,12 +145,71 @@ var fonts []uint8 = []uint8{
 	0xF0, 0x80, 0xF0, 0x80, 0x80, // F
 }
 
+// super-chip 8x10 fonts
+var bigFonts []uint8 = []uint8{
+	0x3C, 0x7E, 0xE7, 0xC3, 0xC3, 0xC3, 0xC3, 0xE7, 0x7E, 0x3C, // 0
+	0x18, 0x38, 0x58, 0x18, 0x18, 0x18, 0x18, 0x18, 0x18, 0x3C, // 1
+	0x3E, 0x7F, 0xC3, 0x06, 0x0C, 0x18, 0x30, 0x60, 0xFF, 0xFF, // 2
+	0x3C, 0x7E, 0xC3, 0x03, 0x0E, 0x0E, 0x03, 0xC3, 0x7E, 0x3C, // 3
+	0x06, 0x0E, 0x1E, 0x36, 0x66, 0xC6, 0xFF, 0xFF, 0x06, 0x06, // 4
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFC, 0xFE, 0x03, 0xC3, 0x7E, 0x3C, // 5
+	0x3E, 0x7C, 0xE0, 0xC0, 0xFC, 0xFE, 0xC3, 0xC3, 0x7E, 0x3C, // 6
+	0xFF, 0xFF, 0x03, 0x06, 0x0C, 0x18, 0x30, 0x60, 0x60, 0x60, // 7
+	0x3C, 0x7E, 0xC3, 0xC3, 0x7E, 0x7E, 0xC3, 0xC3, 0x7E, 0x3C, // 8
+	0x3C, 0x7E, 0xC3, 0xC3, 0x7F, 0x3F, 0x03, 0x03, 0x3E, 0x7C, // 9
+	0x3C, 0x7E, 0xC3, 0xC3, 0xFF, 0xFF, 0xC3, 0xC3, 0xC3, 0xC3, // A
+	0xFC, 0xFE, 0xC3, 0xC3, 0xFE, 0xFE, 0xC3, 0xC3, 0xFE, 0xFC, // B
+	0x3C, 0x7E, 0xC3, 0xC0, 0xC0, 0xC0, 0xC0, 0xC3, 0x7E, 0x3C, // C
+	0xFC, 0xFE, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xFE, 0xFC, // D
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFC, 0xFC, 0xC0, 0xC0, 0xFF, 0xFF, // E
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFC, 0xFC, 0xC0, 0xC0, 0xC0, 0xC0, // F
+}
+
 type EmulatorSettings struct {
-	FrameRate int
-	Rom       []uint8
-	Fill      bool
-	Color     string
-	Keyboard  string
+	Rom []uint8
+	// color of pixels in screenshots and recordings
+	Color string
+	// shows the machine to a player, nil runs without one
+	Frontend frontend.Frontend
+	// run without a frontend even when one is set,
+	// devices are only held in memory
+	Headless bool
+	// instructions executed per second, defaults to DEFAULT_IPS
+	InstructionsPerSecond int
+	// behavior of instructions that differ between interpreters
+	Quirks Quirks
+	// instruction set to run, instructions from later platforms are unknown
+	Platform Platform
+
+	// what to do when an instruction can not be executed
+	FaultPolicy FaultPolicy
+	// called on faults when FaultPolicy is FAULT_CALLBACK, returning
+	// nil resumes execution while an error halts the machine
+	OnFault func(m Machine, f *Fault) error
+
+	// file the save and load state hotkeys use
+	StatePath string
+	// screenshots taken with the hotkey are named this
+	// followed by the time they were taken and .png
+	ScreenshotPath string
+	// width in real pixels of a pixel in screenshots
+	// and recordings, defaults to display.SCALE
+	ScreenshotScale int
+	// recordings started with the hotkey are named this
+	// followed by the time they were started and .gif
+	RecordingPath string
+	// frames of history kept for rewinding, 0 disables rewind
+	RewindFrames int
+	// seeds the random numbers of CXNN so runs can be reproduced,
+	// 0 picks a seed from the clock
+	Seed uint64
+	// replaces the keypad the window sets, such as to play back a movie
+	Keypad keypad.Keypad
+	// set while a movie is recorded or played, loading states and
+	// rewinding are ignored as the movie can not follow them
+	Movie bool
+	// writes executed instructions when set
+	Trace *Trace
 }
 
 type emulator struct {
@@ -121,93 +229,439 @@ type emulator struct {
 	// delay and sound timers
 	dt, st uint8
 
+	// number of instructions executed
+	cycles int
+	// instructions per second, carry accumulates the
+	// remainder when it does not divide evenly into frames
+	ips, carry int
+	// set once a sprite is drawn with the display wait quirk,
+	// ending the frame early
+	vblank bool
+	// set while FX0A waits on a key, ending the frame early so
+	// the timers and frontend carry on while it waits
+	waiting bool
+	// set once FX0A has started waiting on the keypad
+	awaiting bool
+
+	// last rom loaded, kept around for resets
+	rom []uint8
+
+	// guards the machine while Start is running it
+	mu     sync.Mutex
+	paused atomic.Bool
+	// observes execution, usually a debugger
+	hook Hook
+	// fault that caused the machine to trap, if any
+	fault *Fault
+	// set once the program exits
+	halted bool
+
+	// super-chip persistent flags
+	rpl []uint8
+
+	// xo-chip bitplanes drawn to
+	planes uint8
+
+	// random numbers for CXNN, reseeded on reset
+	seed uint64
+	rng  *rng
+
 	settings *EmulatorSettings
 
+	// actions requested from the window
+	hotkeys chan hotkey.Hotkey
+	// recent states, nil when rewind is disabled
+	rewind *rewinder
+	// frames left to keep rewinding for, window updates do not line
+	// up with frames so the rewind key is held over briefly
+	rewindHold int
+	// captures every frame, nil when not recording
+	recording video.Recorder
+
 	// devices
 	speaker speaker.Speaker
 	keypad  keypad.Keypad
 	display display.Display
 }
 
-func Create(settings *EmulatorSettings) *emulator {
-	mem := make([]uint8, MEM_SIZE)
-	// load fonts into memory
-	for i := 0; i < len(fonts); i++ {
-		mem[i+FONT_ADDR] = fonts[i]
-	}
-
+func Create(settings *EmulatorSettings) Machine {
 	speaker := speaker.Create()
-	keypad := keypad.Create()
+	keys := settings.Keypad
+	if keys == nil {
+		keys = keypad.Create()
+	}
 	display := display.Create(ROWS, COLS)
+	hotkeys := make(chan hotkey.Hotkey, HOTKEY_QUEUE)
 
-	go drivers.Create(
-		speaker,
-		keypad,
-		display,
-	).KeypadSettings(
-		settings.Keyboard,
-	).DisplaySettings(
-		settings.FrameRate,
-		settings.Fill,
-		settings.Color,
-	).Start()
-
-	return &emulator{
-		registers: make([]uint8, REGISTERS),
-		mem:       mem,
-		stack:     make([]uint16, STACK_SIZE),
-		settings:  settings,
-		speaker:   speaker,
-		keypad:    keypad,
-		display:   display,
+	ips := settings.InstructionsPerSecond
+	if ips <= 0 {
+		ips = DEFAULT_IPS
+	}
+	em := &emulator{
+		settings: settings,
+		ips:      ips,
+		hotkeys:  hotkeys,
+		speaker:  speaker,
+		keypad:   keys,
+		display:  display,
+	}
+	em.seed = settings.Seed
+	if em.seed == 0 {
+		em.seed = uint64(time.Now().UnixNano())
+	}
+	if settings.RewindFrames > 0 {
+		em.rewind = newRewinder(settings.RewindFrames)
 	}
+	em.Reset()
+	return em
 }
 
 func (em *emulator) Load(rom []uint8) {
+	em.rom = rom
 	for i := 0; i < len(rom); i++ {
 		em.mem[i+ROM_ADDR] = rom[i]
 	}
 	em.pc = ROM_ADDR
 }
 
-func (em *emulator) Start() {
-	clock := time.NewTicker(time.Duration(em.settings.FrameRate) * time.Millisecond)
+// LoadROM loads the rom into memory, returning an error
+// rather than panicking if it does not fit
+func (em *emulator) LoadROM(rom []uint8) error {
+	if len(rom) > len(em.mem)-ROM_ADDR {
+		return fmt.Errorf("rom too large: %d bytes, only %d available", len(rom), len(em.mem)-ROM_ADDR)
+	}
+	em.Load(rom)
+	return nil
+}
+
+// Reset restores the machine to power on state, and
+// reloads the last rom if one was loaded
+func (em *emulator) Reset() {
+	em.registers = make([]uint8, REGISTERS)
+	em.mem = make([]uint8, em.settings.Platform.MemorySize())
+	// load fonts into memory
+	for i := 0; i < len(fonts); i++ {
+		em.mem[i+FONT_ADDR] = fonts[i]
+	}
+	for i := 0; i < len(bigFonts); i++ {
+		em.mem[i+BIG_FONT_ADDR] = bigFonts[i]
+	}
+	em.sp = 0
+	em.stack = make([]uint16, STACK_SIZE)
+	em.rng = newRNG(em.seed)
+	em.i = 0
+	em.pc = ROM_ADDR
+	em.dt, em.st = 0, 0
+	em.cycles, em.carry = 0, 0
+	em.fault = nil
+	em.halted = false
+	em.awaiting = false
+	em.rpl = make([]uint8, em.settings.Platform.flags())
+	em.planes = 1
+
+	em.display.SetResolution(ROWS, COLS)
+	em.display.SelectPlanes(em.planes)
+	em.keypad.Clear()
+	em.speaker.Set(false)
+	em.speaker.SetPattern(nil)
+	em.speaker.SetPitch(speaker.DEFAULT_PITCH)
+	if em.rom != nil {
+		em.Load(em.rom)
+	}
+}
+
+// Start runs the machine on the clock until it halts, returning
+// the fault that stopped it, or nil if the program exited
+func (em *emulator) Start() error {
+	return em.StartContext(context.Background())
+}
+
+// StartContext is Start, also returning nil once ctx is done,
+// even while FX0A is waiting on a key
+func (em *emulator) StartContext(ctx context.Context) error {
+	fe := em.settings.Frontend
+	if fe == nil || em.settings.Headless {
+		fe = frontend.Headless{}
+	}
+	if err := fe.Open(em.display.WindowSize()); err != nil {
+		return err
+	}
+	defer fe.Close()
+
+	clock := time.NewTicker(time.Second / TIMER_HZ)
 	defer clock.Stop()
+	for {
+		select {
+		case <-fe.Quit():
+			return nil
+		case <-ctx.Done():
+			return nil
+		case <-clock.C:
+		}
+		em.pollInput(fe)
+		em.mu.Lock()
+		rewinding := em.handleHotkeys()
+		var err error
+		if rewinding {
+			em.Rewind()
+		} else if !em.paused.Load() {
+			err = em.RunFrame()
+		}
+		fe.Present(em.display)
+		fe.Play(em.speaker)
+		halted := em.halted
+		em.mu.Unlock()
+		if err != nil || halted {
+			return err
+		}
+	}
+}
 
-	i := 0
-	for range clock.C {
-		em.speaker.Set(em.st > 0)
-		if em.speaker.IsActive() {
-			em.st--
+// pollInput passes input from the frontend to the keypad and
+// hotkeys, it is called once a frame before the frame runs
+func (em *emulator) pollInput(fe frontend.Frontend) {
+	in := fe.Poll()
+	for _, e := range in.Keys {
+		if e.Down {
+			em.keypad.Press(e.Key)
+		} else {
+			em.keypad.Release(e.Key)
+		}
+	}
+	for _, h := range in.Hotkeys {
+		hotkey.Send(em.hotkeys, h)
+	}
+}
+
+// handleHotkeys performs the actions requested from the window since
+// the last frame, reporting whether the rewind key is being held.
+// While paused the machine may be in use elsewhere, such as by a
+// debugger stepping it, so hotkeys touching its state are ignored
+func (em *emulator) handleHotkeys() bool {
+	if em.rewindHold > 0 {
+		em.rewindHold--
+	}
+	paused := em.paused.Load()
+	if paused {
+		em.rewindHold = 0
+	}
+	for {
+		select {
+		case h := <-em.hotkeys:
+			if paused && (h == hotkey.SAVE_STATE || h == hotkey.LOAD_STATE || h == hotkey.REWIND) {
+				log.Printf("the machine is paused, resume it to save, load or rewind")
+				continue
+			}
+			if em.settings.Movie && (h == hotkey.LOAD_STATE || h == hotkey.REWIND) {
+				log.Printf("loading states and rewinding would desync the movie")
+				continue
+			}
+			switch h {
+			case hotkey.SAVE_STATE:
+				if err := em.saveStateFile(); err != nil {
+					log.Printf("could not save state: %v", err)
+				}
+			case hotkey.LOAD_STATE:
+				if err := em.loadStateFile(); err != nil {
+					log.Printf("could not load state: %v", err)
+				}
+			case hotkey.REWIND:
+				em.rewindHold = REWIND_HOLD
+			case hotkey.SCREENSHOT:
+				if err := em.screenshotFile(); err != nil {
+					log.Printf("could not take screenshot: %v", err)
+				}
+			case hotkey.RECORD:
+				if err := em.toggleRecording(); err != nil {
+					log.Printf("could not record: %v", err)
+				}
+			}
+		default:
+			return em.rewindHold > 0
 		}
-		if em.dt > 0 {
-			em.dt--
+	}
+}
+
+// Seed returns the seed random numbers are generated from
+func (em *emulator) Seed() uint64 {
+	return em.seed
+}
+
+// Pause stops Start from executing any further frames, once
+// Pause returns the machine can safely be accessed elsewhere
+func (em *emulator) Pause() {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	em.paused.Store(true)
+}
+
+// Resume continues execution after a pause or a trapped fault
+func (em *emulator) Resume() {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	em.paused.Store(false)
+	em.fault = nil
+}
+
+func (em *emulator) Paused() bool {
+	return em.paused.Load()
+}
+
+func (em *emulator) Do(fn func()) {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	fn()
+}
+
+// SetHook installs a hook that observes execution, nil removes it
+func (em *emulator) SetHook(h Hook) {
+	em.hook = h
+}
+
+// Fault returns the fault the machine trapped on, if any
+func (em *emulator) Fault() *Fault {
+	return em.fault
+}
+
+// Halted reports whether the program has exited
+func (em *emulator) Halted() bool {
+	return em.halted
+}
+
+func (em *emulator) Waiting() bool {
+	return em.awaiting
+}
+
+// Run executes n frames as fast as possible without waiting
+// on the clock, this is mostly useful when running headless
+func (em *emulator) Run(n int) error {
+	for f := 0; f < n && !em.paused.Load() && !em.halted; f++ {
+		if err := em.RunFrame(); err != nil {
+			return err
 		}
-		inst, err := em.fetch()
-		if err != nil {
-			log.Fatal(err.Error())
+	}
+	return nil
+}
+
+// RunFrame updates the timers once, then executes
+// however many instructions fit into a single frame
+func (em *emulator) RunFrame() error {
+	if em.rewind != nil && em.rewind.state == nil {
+		em.record()
+	}
+
+	n := em.Tick()
+	for c := 0; c < n && !em.paused.Load() && !em.halted && !em.vblank && !em.waiting; c++ {
+		if err := em.Step(); err != nil {
+			return err
 		}
-		em.execute(inst)
-		if i%10 == 0 {
-			em.keypad.Clear()
+		em.cycles++
+	}
+	if em.rewind != nil {
+		em.record()
+	}
+	if em.recording != nil {
+		if err := em.recording.Frame(em.display); err != nil {
+			log.Printf("could not record frame: %v", err)
+			em.stopRecording()
 		}
-		i++
 	}
+	return nil
+}
+
+// Tick starts a new frame, latching input and counting the timers
+// down once, returning how many instructions fit into the frame
+func (em *emulator) Tick() int {
+	if framer, ok := em.keypad.(keypad.Framer); ok {
+		framer.Frame()
+	}
+	em.speaker.Set(em.st > 0)
+	if em.speaker.IsActive() {
+		em.st--
+	}
+	if em.dt > 0 {
+		em.dt--
+	}
+
+	em.carry += em.ips
+	n := em.carry / TIMER_HZ
+	em.carry %= TIMER_HZ
+	em.vblank = false
+	em.waiting = false
+	return n
+}
+
+// record snapshots the machine for rewinding
+func (em *emulator) record() {
+	var buf bytes.Buffer
+	if err := em.SaveState(&buf); err == nil {
+		em.rewind.push(buf.Bytes())
+	}
+}
+
+// Rewind restores the machine to how it was a frame earlier,
+// returning false once there is no more history to go back to
+func (em *emulator) Rewind() bool {
+	if em.rewind == nil {
+		return false
+	}
+	state, ok := em.rewind.pop()
+	if !ok {
+		return false
+	}
+	if err := em.LoadState(bytes.NewReader(state)); err != nil {
+		log.Printf("could not rewind: %v", err)
+		return false
+	}
+	return true
+}
+
+// Step fetches and executes a single instruction without
+// touching the timers, faults are handled by the fault policy
+func (em *emulator) Step() error {
+	inst, err := em.fetch()
+	// FX0A runs again every frame while it waits, the hook only
+	// sees it the first time
+	if err == nil && em.hook != nil && !em.awaiting && em.hook.Break(em, inst) {
+		em.paused.Store(true)
+		return nil
+	}
+	if err == nil {
+		err = em.execute(inst)
+	}
+	if err != nil {
+		return em.handleFault(&Fault{Err: err, PC: em.pc, Opcode: inst})
+	}
+	return nil
 }
 
 // fetch retrieves two bytes located at pc
 // if two bytes are not available within
 // the available memory, error is returned
 func (em *emulator) fetch() (uint16, error) {
-	if em.pc > MEM_SIZE-2 {
-		return 0, fmt.Errorf("pc out of memory bounds: %d", em.pc)
+	if !em.inMemory(em.pc, 2) {
+		return 0, ErrPCOutOfBounds
 	}
 	p1 := em.mem[em.pc]
 	p2 := em.mem[em.pc+1]
 	return (uint16(p1) << 8) | uint16(p2), nil
 }
 
-func (em *emulator) execute(inst uint16) {
+// inMemory reports whether n bytes starting at addr are addressable
+func (em *emulator) inMemory(addr uint16, n uint16) bool {
+	return int(addr)+int(n) <= len(em.mem)
+}
+
+// execute decodes and runs inst, if it fails
+// pc is left pointing at the instruction
+func (em *emulator) execute(inst uint16) (err error) {
+	if t := em.settings.Trace; t != nil && !em.awaiting && t.traces(em.pc, inst) {
+		before := em.cpu()
+		defer func() {
+			t.write(em, before, em.cpu(), inst, err)
+		}()
+	}
+
 	n1 := inst & N1_MASK
 	n2 := inst & N2_MASK
 	n3 := inst & N3_MASK
@@ -218,32 +672,56 @@ func (em *emulator) execute(inst uint16) {
 	y := n3 >> 4
 	nn := n3 | n4
 
+	if extension(inst) > em.settings.Platform {
+		return ErrUnknownOpcode
+	}
+
 	inc := true
 	switch n1 {
 	case CLS_OR_RET:
-		switch inst {
-		case CLS:
+		switch {
+		case inst == CLS:
 			em.cls()
-		case RET:
-			em.ret()
+		case inst == RET:
+			err = em.ret()
+		case inst&^N4_MASK == SCD_N:
+			em.scdN(n4)
+		case inst&^N4_MASK == SCU_N:
+			em.scuN(n4)
+		case inst == SCR:
+			em.scr()
+		case inst == SCL:
+			em.scl()
+		case inst == EXIT:
+			em.exit()
+			inc = false
+		case inst == LOW:
+			em.low()
+		case inst == HIGH:
+			em.high()
 		default:
-			log.Fatalf("opcode not found: %x", inst)
+			err = ErrUnknownOpcode
 		}
 	case JMP:
 		em.jmp(addr)
 		inc = false
 	case CALL:
-		em.call(addr)
+		err = em.call(addr)
 		inc = false
 	case SEQ_VX_NN:
 		em.seqVxNN(x, nn)
 	case SNE_VX_NN:
 		em.sneVxNN(x, nn)
 	case SEQ_VX_VY:
-		if n4 == 0 {
+		switch n4 {
+		case 0:
 			em.seqVxVy(x, y)
-		} else {
-			log.Fatalf("opcode not found: %x", inst)
+		case SAVE_VX_VY:
+			err = em.saveVxVy(x, y)
+		case LOAD_VX_VY:
+			err = em.loadVxVy(x, y)
+		default:
+			err = ErrUnknownOpcode
 		}
 	case LD_VX_KK:
 		em.ldVxKK(x, nn)
@@ -271,13 +749,13 @@ func (em *emulator) execute(inst uint16) {
 		case SHL_VX_VY:
 			em.shlVxVy(x, y)
 		default:
-			log.Fatalf("opcode not found: %x", inst)
+			err = ErrUnknownOpcode
 		}
 	case SNE_VX_VY:
 		if n4 == 0 {
 			em.sneVxVy(x, y)
 		} else {
-			log.Fatalf("opcode not found: %x", inst)
+			err = ErrUnknownOpcode
 		}
 	case LD_I:
 		em.ldI(addr)
@@ -287,21 +765,21 @@ func (em *emulator) execute(inst uint16) {
 	case RND_VX_KK:
 		em.rndVxKK(x, nn)
 	case DRW_VX_VY_N:
-		em.drawVxVyN(x, y, n4)
+		err = em.drawVxVyN(x, y, n4)
 	case VX_KEY_OPS:
 		if nn == SEQ_VX_KEY_PR {
 			em.seqVxKey(x)
 		} else if n3|n4 == SNE_VX_KEY_PR {
 			em.sneVxKey(x)
 		} else {
-			log.Fatalf("opcode not found: %x", inst)
+			err = ErrUnknownOpcode
 		}
 	case TIMING_OPS:
 		switch nn {
 		case LD_VX_DT:
 			em.ldVxDt(x)
 		case LD_VX_K:
-			em.ldVxK(x)
+			inc = em.ldVxK(x)
 		case LD_DT_VX:
 			em.ldDtVx(x)
 		case LD_ST_VX:
@@ -311,18 +789,44 @@ func (em *emulator) execute(inst uint16) {
 		case LD_F_VX:
 			em.ldFVx(x)
 		case LD_B_VX:
-			em.ldBVx(x)
+			err = em.ldBVx(x)
 		case LD_I_VX:
-			em.ldIVx(x)
+			err = em.ldIVx(x)
 		case LD_VX_I:
-			em.ldVxI(x)
+			err = em.ldVxI(x)
+		case LD_HF_VX:
+			em.ldHFVx(x)
+		case LD_R_VX:
+			err = em.ldRVx(x)
+		case LD_VX_R:
+			err = em.ldVxR(x)
+		case LD_I_LONG:
+			if x != 0 {
+				err = ErrUnknownOpcode
+				break
+			}
+			err = em.ldILong()
+		case PLANE_N:
+			em.planeN(x)
+		case AUDIO:
+			if x != 0 {
+				err = ErrUnknownOpcode
+				break
+			}
+			err = em.audio()
+		case PITCH_VX:
+			em.pitchVx(x)
 		default:
-			log.Fatalf("opcode not found: %x", inst)
+			err = ErrUnknownOpcode
 		}
 	}
+	if err != nil {
+		return err
+	}
 	if inc {
 		em.pc += 2
 	}
+	return nil
 }
 
 // clear screen
@@ -331,9 +835,58 @@ func (em *emulator) cls() {
 }
 
 // return from subroutine
-func (em *emulator) ret() {
+func (em *emulator) ret() error {
+	if em.sp == 0 {
+		return ErrStackUnderflow
+	}
+	if int(em.sp) > len(em.stack) {
+		return ErrStackOverflow
+	}
 	em.sp--
 	em.pc = em.stack[em.sp]
+	return nil
+}
+
+// 0x00CN
+// scroll the display down N pixels
+func (em *emulator) scdN(n uint16) {
+	em.display.Scroll(int(n), 0)
+}
+
+// 0x00DN
+// scroll the display up N pixels
+func (em *emulator) scuN(n uint16) {
+	em.display.Scroll(-int(n), 0)
+}
+
+// 0x00FB
+// scroll the display right 4 pixels
+func (em *emulator) scr() {
+	em.display.Scroll(0, 4)
+}
+
+// 0x00FC
+// scroll the display left 4 pixels
+func (em *emulator) scl() {
+	em.display.Scroll(0, -4)
+}
+
+// 0x00FD
+// exit the interpreter, the machine halts at this instruction
+func (em *emulator) exit() {
+	em.halted = true
+}
+
+// 0x00FE
+// switch the display to low resolution
+func (em *emulator) low() {
+	em.display.SetResolution(ROWS, COLS)
+}
+
+// 0x00FF
+// switch the display to high resolution
+func (em *emulator) high() {
+	em.display.SetResolution(HIRES_ROWS, HIRES_COLS)
 }
 
 // jump program counter to instructed address
@@ -342,18 +895,32 @@ func (em *emulator) jmp(addr uint16) {
 }
 
 // call subroutine
-func (em *emulator) call(addr uint16) {
+func (em *emulator) call(addr uint16) error {
+	if int(em.sp) >= len(em.stack) {
+		return ErrStackOverflow
+	}
 	// move stack pointer to next position, save current position of program counter
 	em.stack[em.sp] = em.pc
 	em.sp++
 	em.pc = addr
+	return nil
+}
+
+// skip moves pc past the next instruction, on xo-chip
+// this may be the four byte long load of i
+func (em *emulator) skip() {
+	em.pc += 2
+	if em.settings.Platform >= PLATFORM_XOCHIP && em.inMemory(em.pc, 2) &&
+		uint16(em.mem[em.pc])<<8|uint16(em.mem[em.pc+1]) == TIMING_OPS|LD_I_LONG {
+		em.pc += 2
+	}
 }
 
 // 0x3XNN
 // skip to next instruction set if register X is equal to NN
 func (em *emulator) seqVxNN(x uint16, nn uint16) {
 	if em.registers[x] == uint8(nn) {
-		em.pc += 2
+		em.skip()
 	}
 }
 
@@ -361,7 +928,7 @@ func (em *emulator) seqVxNN(x uint16, nn uint16) {
 // skip to next instruction set if register X is NOT equal to NN
 func (em *emulator) sneVxNN(x uint16, nn uint16) {
 	if em.registers[x] != uint8(nn) {
-		em.pc += 2
+		em.skip()
 	}
 }
 
@@ -369,10 +936,52 @@ func (em *emulator) sneVxNN(x uint16, nn uint16) {
 // skip to next instruction set if register X is equal to register Y
 func (em *emulator) seqVxVy(x uint16, y uint16) {
 	if em.registers[x] == em.registers[y] {
-		em.pc += 2
+		em.skip()
 	}
 }
 
+// 0x5XY2
+// store registers X through Y to memory starting at i, i is not changed
+func (em *emulator) saveVxVy(x uint16, y uint16) error {
+	n := span(x, y)
+	if !em.inMemory(em.i, n+1) {
+		return ErrMemoryOutOfBounds
+	}
+	for o := uint16(0); o <= n; o++ {
+		em.mem[em.i+o] = em.registers[towards(x, y, o)]
+	}
+	return nil
+}
+
+// 0x5XY3
+// load registers X through Y from memory starting at i, i is not changed
+func (em *emulator) loadVxVy(x uint16, y uint16) error {
+	n := span(x, y)
+	if !em.inMemory(em.i, n+1) {
+		return ErrMemoryOutOfBounds
+	}
+	for o := uint16(0); o <= n; o++ {
+		em.registers[towards(x, y, o)] = em.mem[em.i+o]
+	}
+	return nil
+}
+
+// span returns the distance between two registers
+func span(x uint16, y uint16) uint16 {
+	if x > y {
+		return x - y
+	}
+	return y - x
+}
+
+// towards returns the register o steps from x towards y
+func towards(x uint16, y uint16, o uint16) uint16 {
+	if x > y {
+		return x - o
+	}
+	return x + o
+}
+
 // 0x6XKK
 // load register X with the value of KK
 func (em *emulator) ldVxKK(x uint16, kk uint16) {
@@ -395,77 +1004,98 @@ func (em *emulator) ldVxVy(x uint16, y uint16) {
 // bitwise register X or Y, then store to register X
 func (em *emulator) orVxVy(x uint16, y uint16) {
 	em.registers[x] |= em.registers[y]
+	if em.settings.Quirks.VFReset {
+		em.registers[0xF] = 0
+	}
 }
 
 // 0x8xy2
 // bitwise register X and Y, then store to register X
 func (em *emulator) andVxVy(x uint16, y uint16) {
 	em.registers[x] &= em.registers[y]
+	if em.settings.Quirks.VFReset {
+		em.registers[0xF] = 0
+	}
 }
 
 // 0x8xy3
 // bitwise register X xor Y, then store to register X
 func (em *emulator) xorVxVy(x uint16, y uint16) {
 	em.registers[x] ^= em.registers[y]
+	if em.settings.Quirks.VFReset {
+		em.registers[0xF] = 0
+	}
 }
 
 // 0x8xy4
 // add register X and Y, then store to register X
-// if overflow occurs, set VF register to 1
+// VF is set to 1 if overflow occurs, otherwise 0
+// the flag is written last so it wins when X is VF
 func (em *emulator) addVxVy(x uint16, y uint16) {
 	sum := em.registers[x] + em.registers[y]
+	var carry uint8
 	if sum < em.registers[x] {
-		em.registers[0xF] = 1 // set overflow
+		carry = 1 // set overflow
 	}
 	em.registers[x] = sum
+	em.registers[0xF] = carry
 }
 
 // 0x8xy5
 // subtract register Y from X, then store to register X
 // if underflow occurs, set VF register to 0, otherwise 1
 func (em *emulator) subVxVy(x uint16, y uint16) {
-	diff := em.registers[x] - em.registers[y]
+	var borrow uint8 = 1
 	if em.registers[y] > em.registers[x] {
-		em.registers[0xF] = 0
-	} else {
-		em.registers[0xF] = 1 // set underflow
+		borrow = 0 // set underflow
 	}
-	em.registers[x] = diff
+	em.registers[x] -= em.registers[y]
+	em.registers[0xF] = borrow
 }
 
 // 0x8xy6
 // store the LSB of the value stored in register X to VF
 // then right shift the value of register X by 1, then store to register X
-func (em *emulator) shrVxVy(x uint16, _ uint16) {
-	em.registers[0xF] = em.registers[x] & 0x01
+// with the shift quirk register Y is shifted into register X instead
+func (em *emulator) shrVxVy(x uint16, y uint16) {
+	if em.settings.Quirks.Shift {
+		em.registers[x] = em.registers[y]
+	}
+	flag := em.registers[x] & 0x01
 	em.registers[x] >>= 1
+	em.registers[0xF] = flag
 }
 
 // 0x8xy7
 // subtract register X from Y, then store to register X
 // if underflow occurs, set VF register to 0, otherwise 1
 func (em *emulator) subnVxVy(x uint16, y uint16) {
+	var borrow uint8 = 1
 	if em.registers[x] > em.registers[y] {
-		em.registers[0xF] = 0
-	} else {
-		em.registers[0xF] = 1
+		borrow = 0
 	}
 	em.registers[x] = em.registers[y] - em.registers[x]
+	em.registers[0xF] = borrow
 }
 
 // 0x8xyE
 // store the MSB of the value stored in register X to VF
 // then left shift the value of register X by 1, then store to register X
-func (em *emulator) shlVxVy(x uint16, _ uint16) {
-	em.registers[0xF] = em.registers[x] >> 7
+// with the shift quirk register Y is shifted into register X instead
+func (em *emulator) shlVxVy(x uint16, y uint16) {
+	if em.settings.Quirks.Shift {
+		em.registers[x] = em.registers[y]
+	}
+	flag := em.registers[x] >> 7
 	em.registers[x] <<= 1
+	em.registers[0xF] = flag
 }
 
 // 0x9xy0
 // skip to next instruction set if register X is NOT equal to register Y
 func (em *emulator) sneVxVy(x uint16, y uint16) {
 	if em.registers[x] != em.registers[y] {
-		em.pc += 2
+		em.skip()
 	}
 }
 
@@ -477,38 +1107,69 @@ func (em *emulator) ldI(addr uint16) {
 
 // 0xBnnn
 // set the program counter to addr (nnn) + register v0 value
+// with the jump quirk, register X (0xBxnn) is used in place of v0
 func (em *emulator) jmpV0(addr uint16) {
-	em.pc = addr + uint16(em.registers[0])
+	v := em.registers[0]
+	if em.settings.Quirks.Jump {
+		v = em.registers[addr>>8]
+	}
+	em.pc = addr + uint16(v)
 }
 
 // 0xCxkk
 // set register X to the value of a random number bitwise and KK
 func (em *emulator) rndVxKK(x uint16, kk uint16) {
-	r := uint8(rand.Intn(0x100))
+	r := em.rng.next()
 	em.registers[x] = r & uint8(kk)
 }
 
 // 0xDxyn
 // draw a sprite at register X and Y location, of N height
-func (em *emulator) drawVxVyN(x uint16, y uint16, n uint16) {
-	startc := em.registers[x] % COLS // clamp cx to display width
-	startr := em.registers[y] % ROWS // clamp cy to display height
-	em.registers[0xF] = 0            // clear collision flag
-
-	for rowi := uint8(0); rowi < uint8(n); rowi++ {
-		index := em.i + uint16(rowi)
-		row := em.mem[index]
-		// loop through each bit in a byte (8)
-		for coli := uint8(0); coli < 8; coli++ {
-			// read sprite bits left to right
-			spb := row & (0b1000_0000 >> coli)
-			if spb == 0 {
-				continue
+// on super-chip, a height of 0 draws a 16x16 sprite
+// on xo-chip, one sprite is read for each selected plane
+func (em *emulator) drawVxVyN(x uint16, y uint16, n uint16) error {
+	width, height := uint16(8), n
+	if n == 0 && em.settings.Platform >= PLATFORM_SCHIP {
+		width, height = 16, 16
+	}
+	stride := width / 8 // bytes per sprite row
+	size := height * stride
+	if !em.inMemory(em.i, size*uint16(bits.OnesCount8(em.planes))) {
+		return ErrMemoryOutOfBounds
+	}
+	rows, cols := em.display.WindowSize()
+	startc := em.registers[x] % uint8(cols) // clamp cx to display width
+	startr := em.registers[y] % uint8(rows) // clamp cy to display height
+	em.registers[0xF] = 0                   // clear collision flag
+
+	addr := em.i
+	for plane := 0; plane < display.PLANES; plane++ {
+		if em.planes&(1<<plane) == 0 {
+			continue
+		}
+		em.display.SelectPlanes(1 << plane)
+		for rowi := uint8(0); rowi < uint8(height); rowi++ {
+			index := addr + uint16(rowi)*stride
+			row := uint16(em.mem[index])
+			if stride == 2 {
+				row = row<<8 | uint16(em.mem[index+1])
 			}
+			// loop through each bit in the row, 8 or 16
+			for coli := uint8(0); coli < uint8(width); coli++ {
+				// read sprite bits left to right
+				spb := row & (1 << (width - 1) >> coli)
+				if spb == 0 {
+					continue
+				}
 
-			pixelr := (startr + rowi) % COLS
-			pixelc := startc + coli
-			if startc+coli < COLS && startr+rowi < ROWS {
+				pixelr := startr + rowi
+				pixelc := startc + coli
+				if em.settings.Quirks.Wrap {
+					pixelr %= uint8(rows)
+					pixelc %= uint8(cols)
+				} else if int(pixelc) >= cols || int(pixelr) >= rows {
+					continue // clip at the edges
+				}
 				pixel := em.display.Get(pixelr, pixelc)
 				if pixel {
 					em.display.Set(emit.OFF, pixelr, pixelc)
@@ -518,7 +1179,13 @@ func (em *emulator) drawVxVyN(x uint16, y uint16, n uint16) {
 				}
 			}
 		}
+		addr += size
+	}
+	em.display.SelectPlanes(em.planes)
+	if em.settings.Quirks.DisplayWait {
+		em.vblank = true
 	}
+	return nil
 }
 
 // 0xEX9E
@@ -527,7 +1194,7 @@ func (em *emulator) seqVxKey(x uint16) {
 	key := em.registers[x]
 	// check if key is pressed
 	if em.keypad.Get(key) {
-		em.pc += 2
+		em.skip()
 	}
 }
 
@@ -537,7 +1204,7 @@ func (em *emulator) sneVxKey(x uint16) {
 	key := em.registers[x]
 	// check if key is not pressed
 	if !em.keypad.Get(key) {
-		em.pc += 2
+		em.skip()
 	}
 }
 
@@ -548,10 +1215,25 @@ func (em *emulator) ldVxDt(x uint16) {
 }
 
 // 0xFX0A
-// await a keypress, and assign keycode to register X
-func (em *emulator) ldVxK(x uint16) {
-	kaddr := em.keypad.Next()
+// await a keypress, and assign keycode to register X. Nothing blocks
+// or wakes the machine, input only arrives once a frame so FX0A ends
+// the frame and runs again on the next, checking the keypad until a
+// key has been pressed and released, reporting whether it has. The
+// timers and frontend keep running meanwhile, and StartContext still
+// returns once its context is done
+func (em *emulator) ldVxK(x uint16) bool {
+	if !em.awaiting {
+		em.keypad.Await()
+		em.awaiting = true
+	}
+	kaddr, ok := em.keypad.Awaited()
+	if !ok {
+		em.waiting = true
+		return false
+	}
+	em.awaiting = false
 	em.registers[x] = kaddr
+	return true
 }
 
 // 0xFX15
@@ -578,15 +1260,24 @@ func (em *emulator) addIVx(x uint16) {
 }
 
 // 0xFX29
-// add i and value of register X, then store to i
+// set i to the font sprite for the hex digit stored in register X
 func (em *emulator) ldFVx(x uint16) {
-	em.i = uint16(em.registers[x]) * 5
+	em.i = FONT_ADDR + uint16(em.registers[x]&0xF)*5
+}
+
+// 0xFX30
+// set i to the large font sprite for the hex digit stored in register X
+func (em *emulator) ldHFVx(x uint16) {
+	em.i = BIG_FONT_ADDR + uint16(em.registers[x]&0xF)*10
 }
 
 // 0xFX33
 // store BCD representation of the value stored in register X
 // in memory locations I, I+1, and I+2.
-func (em *emulator) ldBVx(x uint16) {
+func (em *emulator) ldBVx(x uint16) error {
+	if !em.inMemory(em.i, 3) {
+		return ErrMemoryOutOfBounds
+	}
 	bcd := em.registers[x]
 	least := bcd % 10
 	mid := ((bcd % 100) - least) / 10
@@ -596,22 +1287,91 @@ func (em *emulator) ldBVx(x uint16) {
 	em.mem[em.i] = most
 	em.mem[em.i+1] = mid
 	em.mem[em.i+2] = least
+	return nil
 }
 
 // 0xFX55
 // store the values in registers 0-X to memory starting at i
-func (em *emulator) ldIVx(x uint16) {
+// with the memory quirk, i is left pointing after register X
+func (em *emulator) ldIVx(x uint16) error {
+	if !em.inMemory(em.i, x+1) {
+		return ErrMemoryOutOfBounds
+	}
 	for i := uint16(0); i <= x; i++ {
 		em.mem[em.i+i] = em.registers[i]
 	}
-	em.i = x + 1
+	if em.settings.Quirks.MemoryIncrement {
+		em.i += x + 1
+	}
+	return nil
 }
 
 // 0xFX65
 // store the values in memory starting at i into registers 0-X
-func (em *emulator) ldVxI(x uint16) {
+// with the memory quirk, i is left pointing after register X
+func (em *emulator) ldVxI(x uint16) error {
+	if !em.inMemory(em.i, x+1) {
+		return ErrMemoryOutOfBounds
+	}
 	for i := uint16(0); i <= x; i++ {
 		em.registers[i] = em.mem[em.i+i]
 	}
-	em.i = x + 1
+	if em.settings.Quirks.MemoryIncrement {
+		em.i += x + 1
+	}
+	return nil
+}
+
+// 0xFX75
+// store the values in registers 0-X to the rpl flags
+func (em *emulator) ldRVx(x uint16) error {
+	if int(x) >= len(em.rpl) {
+		return ErrMemoryOutOfBounds
+	}
+	copy(em.rpl, em.registers[:x+1])
+	return nil
+}
+
+// 0xFX85
+// load the rpl flags into registers 0-X
+func (em *emulator) ldVxR(x uint16) error {
+	if int(x) >= len(em.rpl) {
+		return ErrMemoryOutOfBounds
+	}
+	copy(em.registers, em.rpl[:x+1])
+	return nil
+}
+
+// 0xF000 NNNN
+// load i with the 16 bit address stored after the instruction
+func (em *emulator) ldILong() error {
+	if !em.inMemory(em.pc, 4) {
+		return ErrMemoryOutOfBounds
+	}
+	em.i = uint16(em.mem[em.pc+2])<<8 | uint16(em.mem[em.pc+3])
+	em.pc += 2
+	return nil
+}
+
+// 0xFN01
+// select the bitplanes N further drawing acts on
+func (em *emulator) planeN(n uint16) {
+	em.planes = uint8(n) & (1<<display.PLANES - 1)
+	em.display.SelectPlanes(em.planes)
+}
+
+// 0xF002
+// load the 16 byte audio pattern from memory starting at i
+func (em *emulator) audio() error {
+	if !em.inMemory(em.i, speaker.PATTERN_SIZE) {
+		return ErrMemoryOutOfBounds
+	}
+	em.speaker.SetPattern(em.mem[em.i : em.i+speaker.PATTERN_SIZE])
+	return nil
+}
+
+// 0xFX3A
+// set the audio pitch to the value of register X
+func (em *emulator) pitchVx(x uint16) {
+	em.speaker.SetPitch(em.registers[x])
 }
@@ -1,9 +1,14 @@
 package emulator
 
 import (
-	"math/rand"
+	"context"
+	"errors"
+	"os"
 	"testing"
+	"time"
 
+	"github.com/bchadwic/chip8/internal/display/emit"
+	"github.com/bchadwic/chip8/internal/frontend"
 	"github.com/bchadwic/chip8/internal/mocks"
 	"github.com/stretchr/testify/assert"
 )
@@ -19,14 +24,65 @@ func testEmulator() *emulator {
 		registers: make([]uint8, REGISTERS),
 		mem:       mem,
 		stack:     make([]uint16, STACK_SIZE),
+		settings:  &EmulatorSettings{},
+		rng:       newRNG(32),
 	}
 }
 
 func Test_Create(t *testing.T) {
 	em := Create(&EmulatorSettings{})
 	assert.NotNil(t, em)
-	assert.Equal(t, len(em.registers), REGISTERS)
-	assert.Equal(t, len(em.stack), STACK_SIZE)
+	assert.Equal(t, len(em.Registers()), REGISTERS)
+	assert.Equal(t, len(em.Stack()), STACK_SIZE)
+}
+
+func Test_Run(t *testing.T) {
+	rom, err := os.ReadFile("../roms/ibm.ch8")
+	assert.Nil(t, err)
+
+	em := Create(&EmulatorSettings{Headless: true})
+	em.Load(rom)
+	assert.Nil(t, em.Run(100))
+
+	lit := 0
+	for _, pixel := range em.Display().Pixels() {
+		if pixel.Status == emit.ON {
+			lit++
+		}
+	}
+	assert.NotZero(t, lit)
+}
+
+func Test_Start_frontend(t *testing.T) {
+	rom, err := os.ReadFile("../roms/ibm.ch8")
+	assert.Nil(t, err)
+
+	fe := &mocks.TestFrontend{
+		Out_QuitChan:  make(chan struct{}),
+		Out_PollInput: frontend.Input{Keys: []frontend.KeyEvent{{Key: 0x5, Down: true}}},
+	}
+	em := Create(&EmulatorSettings{Frontend: fe})
+	assert.Nil(t, em.LoadROM(rom))
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		close(fe.Out_QuitChan)
+	}()
+	assert.Nil(t, em.Start())
+
+	assert.Equal(t, ROWS, fe.In_OpenRows)
+	assert.Equal(t, COLS, fe.In_OpenCols)
+	assert.Positive(t, fe.In_PresentCount)
+	assert.Equal(t, fe.In_PresentCount, fe.In_PlayCount)
+	assert.True(t, fe.In_Close)
+	// held keys stay down rather than being cleared
+	assert.True(t, em.Keypad().Get(0x5))
+}
+
+func Test_Start_frontend_error(t *testing.T) {
+	fe := &mocks.TestFrontend{Out_OpenErr: errors.New("no display")}
+	em := Create(&EmulatorSettings{Frontend: fe})
+	assert.EqualError(t, em.Start(), "no display")
+	assert.False(t, fe.In_Close)
 }
 
 func Test_Load(t *testing.T) {
@@ -35,6 +91,37 @@ func Test_Load(t *testing.T) {
 	assert.Equal(t, em.pc, uint16(ROM_ADDR))
 }
 
+func Test_LoadROM(t *testing.T) {
+	em := Create(&EmulatorSettings{Headless: true})
+	assert.Nil(t, em.LoadROM([]uint8{0x65, 0x05}))
+	assert.Equal(t, uint8(0x65), em.Memory(ROM_ADDR))
+	assert.NotNil(t, em.LoadROM(make([]uint8, MEM_SIZE)))
+}
+
+func Test_Reset(t *testing.T) {
+	em := Create(&EmulatorSettings{Headless: true})
+	em.Load([]uint8{0x65, 0x05})
+	assert.Nil(t, em.Step())
+	assert.Equal(t, uint8(0x05), em.Register(5))
+
+	em.Reset()
+	assert.Equal(t, uint8(0), em.Register(5))
+	assert.Equal(t, uint16(ROM_ADDR), em.PC())
+	assert.Equal(t, uint8(0x65), em.Memory(ROM_ADDR))
+	assert.Equal(t, fonts[0], em.Memory(FONT_ADDR))
+}
+
+func Test_Step(t *testing.T) {
+	em := Create(&EmulatorSettings{Headless: true})
+	em.Load([]uint8{0x65, 0x05, 0x75, 0x01})
+	em.SetDT(3)
+	assert.Nil(t, em.Step())
+	assert.Nil(t, em.Step())
+	assert.Equal(t, uint8(0x06), em.Register(5))
+	assert.Equal(t, uint16(ROM_ADDR+4), em.PC())
+	assert.Equal(t, uint8(3), em.DT())
+}
+
 func Test_fetch(t *testing.T) {
 	em := testEmulator()
 	em.Load([]uint8{0x65, 0x05})
@@ -51,6 +138,82 @@ func Test_cls(t *testing.T) {
 	assert.True(t, display.In_Clear)
 }
 
+func Test_scroll(t *testing.T) {
+	em := testEmulator()
+	display := &mocks.TestDisplay{}
+	em.display = display
+	em.scdN(3)
+	assert.Equal(t, 3, display.In_ScrollRows)
+	em.scl()
+	assert.Equal(t, -4, display.In_ScrollCols)
+	em.scr()
+	assert.Equal(t, 4, display.In_ScrollCols)
+}
+
+func Test_high(t *testing.T) {
+	em := testEmulator()
+	display := &mocks.TestDisplay{}
+	em.display = display
+	em.high()
+	assert.Equal(t, uint8(HIRES_ROWS), display.In_SetResolutionRows)
+	assert.Equal(t, uint8(HIRES_COLS), display.In_SetResolutionCols)
+	em.low()
+	assert.Equal(t, uint8(ROWS), display.In_SetResolutionRows)
+}
+
+func Test_exit(t *testing.T) {
+	em := Create(&EmulatorSettings{Headless: true, Platform: PLATFORM_SCHIP})
+	em.Load([]uint8{0x00, 0xFD})
+	assert.Nil(t, em.Run(10))
+	assert.True(t, em.Halted())
+	assert.Equal(t, uint16(ROM_ADDR), em.PC())
+}
+
+func Test_drawVxVyN_large(t *testing.T) {
+	em := Create(&EmulatorSettings{Headless: true, Platform: PLATFORM_SCHIP}).(*emulator)
+	em.high()
+	em.i = 0x300
+	for i := uint16(0); i < 32; i++ {
+		em.mem[em.i+i] = 0xFF
+	}
+	em.registers[0] = 120
+	assert.Nil(t, em.drawVxVyN(0, 1, 0))
+	assert.Equal(t, emit.ON, em.display.Get(15, 127))
+	assert.Equal(t, emit.OFF, em.display.Get(16, 127))
+	assert.Equal(t, uint8(0), em.registers[0xF])
+	assert.Nil(t, em.drawVxVyN(0, 1, 0))
+	assert.Equal(t, emit.OFF, em.display.Get(15, 127))
+	assert.Equal(t, uint8(1), em.registers[0xF])
+}
+
+func Test_scuN(t *testing.T) {
+	em := testEmulator()
+	display := &mocks.TestDisplay{}
+	em.display = display
+	em.scuN(2)
+	assert.Equal(t, -2, display.In_ScrollRows)
+}
+
+func Test_drawVxVyN_planes(t *testing.T) {
+	em := Create(&EmulatorSettings{Headless: true, Platform: PLATFORM_XOCHIP}).(*emulator)
+	em.i = 0x300
+	em.mem[0x300] = 0x80 // first plane
+	em.mem[0x301] = 0xC0 // second plane
+	em.planeN(3)
+	assert.Nil(t, em.drawVxVyN(0, 1, 1))
+
+	pixels := em.display.Pixels()
+	assert.Equal(t, uint8(0b11), pixels[0].Color)
+	assert.Equal(t, uint8(0b10), pixels[1].Color)
+	assert.Equal(t, uint8(0), em.registers[0xF])
+
+	// clearing only the first plane leaves the second alone
+	em.planeN(1)
+	em.cls()
+	pixels = em.display.Pixels()
+	assert.Equal(t, uint8(0b10), pixels[0].Color)
+}
+
 func Test_ret(t *testing.T) {
 	em := testEmulator()
 	em.stack[2] = 0xFA
@@ -107,6 +270,49 @@ func Test_seqVxVy(t *testing.T) {
 	assert.Equal(t, em.registers[3], uint8(0x32))
 }
 
+func Test_saveVxVy(t *testing.T) {
+	em := testEmulator()
+	em.i = 0x300
+	em.registers[2], em.registers[3], em.registers[4] = 2, 3, 4
+	assert.Nil(t, em.saveVxVy(2, 4))
+	assert.Equal(t, []uint8{2, 3, 4}, em.mem[0x300:0x303])
+	assert.Nil(t, em.saveVxVy(4, 2))
+	assert.Equal(t, []uint8{4, 3, 2}, em.mem[0x300:0x303])
+	assert.Equal(t, uint16(0x300), em.i)
+}
+
+func Test_loadVxVy(t *testing.T) {
+	em := testEmulator()
+	em.i = 0x300
+	em.mem[0x300], em.mem[0x301] = 7, 8
+	assert.Nil(t, em.loadVxVy(6, 5))
+	assert.Equal(t, uint8(7), em.registers[6])
+	assert.Equal(t, uint8(8), em.registers[5])
+}
+
+func Test_skip_long(t *testing.T) {
+	em := Create(&EmulatorSettings{Headless: true, Platform: PLATFORM_XOCHIP})
+	// skip over the long load of i
+	em.Load([]uint8{0x30, 0x00, 0xF0, 0x00, 0x12, 0x34, 0xF0, 0x00, 0x43, 0x21})
+	assert.Nil(t, em.Step())
+	assert.Equal(t, uint16(ROM_ADDR+6), em.PC())
+	assert.Nil(t, em.Step())
+	assert.Equal(t, uint16(0x4321), em.I())
+	assert.Equal(t, uint16(ROM_ADDR+10), em.PC())
+}
+
+func Test_audio(t *testing.T) {
+	em := Create(&EmulatorSettings{Headless: true, Platform: PLATFORM_XOCHIP}).(*emulator)
+	em.i = 0x300
+	em.mem[0x300] = 0xAA
+	em.registers[1] = 100
+	assert.Nil(t, em.audio())
+	em.pitchVx(1)
+	pattern, pitch := em.speaker.Pattern()
+	assert.Equal(t, uint8(0xAA), pattern[0])
+	assert.Equal(t, uint8(100), pitch)
+}
+
 func Test_ldVxKK(t *testing.T) {
 	em := testEmulator()
 	em.registers[3] = uint8(0x1)
@@ -160,6 +366,30 @@ func Test_addVxVy(t *testing.T) {
 	em.addVxVy(3, 4)
 	assert.Equal(t, em.registers[3], uint8(4))
 	assert.Equal(t, em.registers[0xF], uint8(1))
+
+	// no overflow clears VF
+	em.addVxVy(3, 4)
+	assert.Equal(t, em.registers[3], uint8(14))
+	assert.Equal(t, em.registers[0xF], uint8(0))
+}
+
+func Test_flags_vf(t *testing.T) {
+	// the flag replaces the result when X is VF
+	ops := map[string]func(em *emulator){
+		"add":  func(em *emulator) { em.addVxVy(0xF, 0) },
+		"sub":  func(em *emulator) { em.subVxVy(0xF, 0) },
+		"shr":  func(em *emulator) { em.shrVxVy(0xF, 0) },
+		"subn": func(em *emulator) { em.subnVxVy(0xF, 0) },
+		"shl":  func(em *emulator) { em.shlVxVy(0xF, 0) },
+	}
+	want := map[string]uint8{"add": 1, "sub": 1, "shr": 1, "subn": 0, "shl": 1}
+	for name, op := range ops {
+		em := testEmulator()
+		em.registers[0xF] = 0xFF
+		em.registers[0] = 0x01
+		op(em)
+		assert.Equal(t, want[name], em.registers[0xF], name)
+	}
 }
 
 func Test_subVxVy(t *testing.T) {
@@ -223,11 +453,48 @@ func Test_jmpV0(t *testing.T) {
 func Test_rndVxKK(t *testing.T) {
 	em := testEmulator()
 	em.pc = 3
-	// deprecated, but I don't think I care yet
-	rand.Seed(32)
 	em.registers[3] = 2
 	em.rndVxKK(3, 6)
 	assert.Equal(t, em.pc, uint16(3))
+	assert.Equal(t, uint8(0xDA&6), em.registers[3])
+
+	// the same seed gives the same numbers
+	em.rng = newRNG(32)
+	em.rndVxKK(3, 0xFF)
+	assert.Equal(t, uint8(0xDA), em.registers[3])
+}
+
+func Test_ldVxK(t *testing.T) {
+	em := Create(&EmulatorSettings{Headless: true}).(*emulator)
+	em.Load([]uint8{
+		0xF3, 0x0A, // LD V3, K
+		0x64, 0x01, // LD V4, 0x01
+		0x12, 0x04, // JMP 0x204
+	})
+	em.SetDT(10)
+	// frames go by while it waits, with the timers counting down
+	assert.Nil(t, em.Run(3))
+	assert.Equal(t, uint16(0x200), em.PC())
+	assert.Equal(t, uint8(7), em.DT())
+
+	em.Keypad().Press(0x7)
+	assert.Nil(t, em.Run(1))
+	assert.Equal(t, uint16(0x200), em.PC())
+
+	em.Keypad().Release(0x7)
+	assert.Nil(t, em.Run(1))
+	assert.Equal(t, uint8(0x7), em.Register(3))
+	assert.Equal(t, uint8(0x1), em.Register(4))
+}
+
+func Test_StartContext(t *testing.T) {
+	em := Create(&EmulatorSettings{Headless: true}).(*emulator)
+	em.Load([]uint8{0xF0, 0x0A}) // LD V0, K
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.Nil(t, em.StartContext(ctx))
+	assert.Equal(t, uint16(0x200), em.PC())
+	assert.True(t, em.Waiting())
 }
 
 func Test_ldVxDt(t *testing.T) {
@@ -268,19 +535,65 @@ func Test_ldFVx(t *testing.T) {
 	em.i = 3
 	em.registers[3] = 2
 	em.ldFVx(3)
-	assert.Equal(t, em.i, uint16(2)*5)
+	assert.Equal(t, em.i, uint16(FONT_ADDR+2*5))
+	assert.Equal(t, em.mem[em.i], fonts[2*5])
+}
+
+func Test_ldHFVx(t *testing.T) {
+	em := testEmulator()
+	em.registers[3] = 2
+	em.ldHFVx(3)
+	assert.Equal(t, em.i, uint16(BIG_FONT_ADDR+2*10))
+}
+
+func Test_ldRVx(t *testing.T) {
+	em := Create(&EmulatorSettings{Headless: true}).(*emulator)
+	em.registers[0] = 4
+	em.registers[1] = 5
+	assert.Nil(t, em.ldRVx(1))
+	em.registers[0], em.registers[1] = 0, 0
+	assert.Nil(t, em.ldVxR(1))
+	assert.Equal(t, uint8(4), em.registers[0])
+	assert.Equal(t, uint8(5), em.registers[1])
+	assert.ErrorIs(t, em.ldRVx(RPL_FLAGS), ErrMemoryOutOfBounds)
 }
 
 func Test_ldIVx(t *testing.T) {
 	em := testEmulator()
-	em.i = 3
+	em.i = 0x300
+	em.registers[2] = 7
 	em.ldIVx(3)
-	assert.Equal(t, em.i, uint16(4))
+	assert.Equal(t, em.mem[0x302], uint8(7))
+	assert.Equal(t, em.i, uint16(0x300))
 }
 
 func Test_ldVxI(t *testing.T) {
 	em := testEmulator()
-	em.i = 3
+	em.i = 0x300
+	em.mem[0x302] = 7
 	em.ldVxI(3)
-	assert.Equal(t, em.i, uint16(4))
+	assert.Equal(t, em.registers[2], uint8(7))
+	assert.Equal(t, em.i, uint16(0x300))
+}
+
+func Test_RunFrame(t *testing.T) {
+	// add one to v2, then jump back
+	em := Create(&EmulatorSettings{Headless: true, InstructionsPerSecond: 90})
+	em.Load([]uint8{0x72, 0x01, 0x12, 0x00})
+	em.SetDT(10)
+
+	// 90 instructions per second is one and a half per frame
+	assert.Nil(t, em.RunFrame())
+	assert.Equal(t, uint8(9), em.DT())
+	assert.Equal(t, uint8(1), em.Register(2))
+	assert.Nil(t, em.RunFrame())
+	assert.Equal(t, uint8(8), em.DT())
+	assert.Equal(t, uint8(2), em.Register(2))
+
+	// timers tick 60 times no matter how fast the cpu runs
+	em = Create(&EmulatorSettings{Headless: true, InstructionsPerSecond: 6000})
+	em.Load([]uint8{0x12, 0x00})
+	em.SetDT(100)
+	assert.Nil(t, em.Run(TIMER_HZ))
+	assert.Equal(t, uint8(100-TIMER_HZ), em.DT())
 }
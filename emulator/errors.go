@@ -0,0 +1,81 @@
+package emulator
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownOpcode     = errors.New("opcode not found")
+	ErrPCOutOfBounds     = errors.New("pc out of memory bounds")
+	ErrStackOverflow     = errors.New("stack overflow")
+	ErrStackUnderflow    = errors.New("stack underflow")
+	ErrMemoryOutOfBounds = errors.New("memory access out of bounds")
+)
+
+// Fault is returned when an instruction cannot be executed,
+// it records where the machine was when it went wrong
+type Fault struct {
+	Err    error
+	PC     uint16
+	Opcode uint16
+}
+
+func (f *Fault) Error() string {
+	return fmt.Sprintf("%v: pc %#04x, opcode %#04x", f.Err, f.PC, f.Opcode)
+}
+
+func (f *Fault) Unwrap() error {
+	return f.Err
+}
+
+type FaultPolicy int
+
+const (
+	FAULT_HALT     FaultPolicy = iota // stop the machine and return the fault
+	FAULT_SKIP                        // skip over the faulting instruction
+	FAULT_TRAP                        // pause the machine so it can be inspected
+	FAULT_CALLBACK                    // hand the fault to EmulatorSettings.OnFault
+)
+
+// ParseFaultPolicy converts the name of a fault policy to its value
+func ParseFaultPolicy(name string) (FaultPolicy, error) {
+	switch name {
+	case "halt":
+		return FAULT_HALT, nil
+	case "skip":
+		return FAULT_SKIP, nil
+	case "trap":
+		return FAULT_TRAP, nil
+	case "callback":
+		return FAULT_CALLBACK, nil
+	}
+	return FAULT_HALT, fmt.Errorf("unknown fault policy: %s", name)
+}
+
+// handleFault applies the configured fault policy, a non nil
+// error means the machine can not continue
+func (em *emulator) handleFault(f *Fault) error {
+	switch em.settings.FaultPolicy {
+	case FAULT_SKIP:
+		// there is nothing to skip to when pc itself is out of bounds
+		if errors.Is(f, ErrPCOutOfBounds) {
+			return f
+		}
+		em.pc += 2
+		return nil
+	case FAULT_TRAP:
+		em.paused.Store(true)
+		em.fault = f
+		if em.hook != nil {
+			em.hook.Trap(em, f)
+		}
+		return nil
+	case FAULT_CALLBACK:
+		if em.settings.OnFault == nil {
+			return f
+		}
+		return em.settings.OnFault(em, f)
+	}
+	return f
+}
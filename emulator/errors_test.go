@@ -0,0 +1,99 @@
+package emulator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Step_unknownOpcode(t *testing.T) {
+	em := Create(&EmulatorSettings{Headless: true})
+	em.Load([]uint8{0x0F, 0xFF})
+	err := em.Step()
+	var fault *Fault
+	assert.True(t, errors.As(err, &fault))
+	assert.ErrorIs(t, err, ErrUnknownOpcode)
+	assert.Equal(t, uint16(ROM_ADDR), fault.PC)
+	assert.Equal(t, uint16(0x0FFF), fault.Opcode)
+	assert.Equal(t, uint16(ROM_ADDR), em.PC())
+}
+
+func Test_Step_pcOutOfBounds(t *testing.T) {
+	em := Create(&EmulatorSettings{Headless: true, FaultPolicy: FAULT_SKIP})
+	em.SetPC(MEM_SIZE - 1)
+	assert.ErrorIs(t, em.Step(), ErrPCOutOfBounds)
+}
+
+func Test_call_overflow(t *testing.T) {
+	em := testEmulator()
+	em.sp = STACK_SIZE
+	assert.ErrorIs(t, em.call(0x300), ErrStackOverflow)
+	assert.Equal(t, uint8(STACK_SIZE), em.sp)
+}
+
+func Test_ret_underflow(t *testing.T) {
+	em := testEmulator()
+	assert.ErrorIs(t, em.ret(), ErrStackUnderflow)
+}
+
+func Test_ret_outOfBounds(t *testing.T) {
+	em := Create(&EmulatorSettings{Headless: true}).(*emulator)
+	em.Load([]uint8{0x00, 0xEE})
+	// a stack pointer set past the stack by a restored state or the debugger
+	em.sp = STACK_SIZE + 4
+	assert.ErrorIs(t, em.Step(), ErrStackOverflow)
+	assert.Equal(t, uint8(STACK_SIZE+4), em.sp)
+}
+
+func Test_ldIVx_outOfBounds(t *testing.T) {
+	em := testEmulator()
+	em.i = MEM_SIZE - 2
+	assert.ErrorIs(t, em.ldIVx(3), ErrMemoryOutOfBounds)
+}
+
+func Test_handleFault_skip(t *testing.T) {
+	em := Create(&EmulatorSettings{Headless: true, FaultPolicy: FAULT_SKIP})
+	em.Load([]uint8{0x00, 0xEE, 0x65, 0x05})
+	assert.Nil(t, em.Step())
+	assert.Nil(t, em.Step())
+	assert.Equal(t, uint8(0x05), em.Register(5))
+}
+
+func Test_handleFault_trap(t *testing.T) {
+	em := Create(&EmulatorSettings{Headless: true, FaultPolicy: FAULT_TRAP})
+	em.Load([]uint8{0x00, 0xEE})
+	assert.Nil(t, em.Run(5))
+	assert.True(t, em.Paused())
+	assert.ErrorIs(t, em.Fault(), ErrStackUnderflow)
+	assert.Equal(t, uint16(ROM_ADDR), em.PC())
+
+	em.Resume()
+	assert.False(t, em.Paused())
+	assert.Nil(t, em.Fault())
+}
+
+func Test_handleFault_callback(t *testing.T) {
+	var got *Fault
+	em := Create(&EmulatorSettings{
+		Headless:    true,
+		FaultPolicy: FAULT_CALLBACK,
+		OnFault: func(m Machine, f *Fault) error {
+			got = f
+			m.SetPC(f.PC + 2)
+			return nil
+		},
+	})
+	em.Load([]uint8{0x00, 0xEE})
+	assert.Nil(t, em.Step())
+	assert.ErrorIs(t, got, ErrStackUnderflow)
+	assert.Equal(t, uint16(ROM_ADDR+2), em.PC())
+}
+
+func Test_ParseFaultPolicy(t *testing.T) {
+	policy, err := ParseFaultPolicy("trap")
+	assert.Nil(t, err)
+	assert.Equal(t, FAULT_TRAP, policy)
+	_, err = ParseFaultPolicy("explode")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,189 @@
+package emulator
+
+import (
+	"context"
+	"io"
+
+	"github.com/bchadwic/chip8/internal/display"
+	"github.com/bchadwic/chip8/internal/keypad"
+	"github.com/bchadwic/chip8/internal/speaker"
+)
+
+// Machine is a CHIP-8 interpreter whose CPU state
+// can be inspected and driven from outside the package
+type Machine interface {
+	Load(rom []uint8)
+	LoadROM(rom []uint8) error
+	Reset()
+	Start() error
+	StartContext(ctx context.Context) error
+	Pause()
+	Resume()
+	Paused() bool
+	// Do runs fn holding the lock StartContext runs each frame
+	// under, so a paused machine can be stepped from elsewhere
+	Do(fn func())
+	Fault() *Fault
+	Halted() bool
+	// Waiting reports whether FX0A is waiting on a key
+	Waiting() bool
+	SetHook(h Hook)
+	SaveState(w io.Writer) error
+	LoadState(r io.Reader) error
+	Rewind() bool
+	Seed() uint64
+	Screenshot(path string) error
+	StartRecording(path string) error
+	StopRecording() error
+	Run(n int) error
+	RunFrame() error
+	Tick() int
+	Step() error
+
+	Registers() []uint8
+	// registers are 0x0-0xF, others read as 0 and writes to them are ignored
+	Register(x uint8) uint8
+	SetRegister(x, v uint8)
+	// addresses are below MemorySize, others read as 0
+	// and writes to them are ignored
+	Memory(addr uint16) uint8
+	SetMemory(addr uint16, v uint8)
+	MemorySize() int
+	Stack() []uint16
+	// levels are below STACK_SIZE, writes to others are ignored
+	SetStack(level uint8, addr uint16)
+	SP() uint8
+	// the stack pointer is at most STACK_SIZE, writes above it are ignored
+	SetSP(sp uint8)
+	I() uint16
+	SetI(i uint16)
+	PC() uint16
+	SetPC(pc uint16)
+	DT() uint8
+	SetDT(dt uint8)
+	ST() uint8
+	SetST(st uint8)
+
+	Display() display.Display
+	Keypad() keypad.Keypad
+	Speaker() speaker.Speaker
+}
+
+// Hook lets a debugger observe the machine, its methods are
+// called from whichever goroutine is running the machine
+type Hook interface {
+	// Break is consulted before each instruction executes,
+	// returning true pauses the machine before it runs
+	Break(m Machine, inst uint16) bool
+	// Trap is notified when a fault pauses the machine
+	Trap(m Machine, f *Fault)
+}
+
+// Registers returns a copy of the general purpose registers v0-vF
+func (em *emulator) Registers() []uint8 {
+	registers := make([]uint8, len(em.registers))
+	copy(registers, em.registers)
+	return registers
+}
+
+func (em *emulator) Register(x uint8) uint8 {
+	if int(x) >= len(em.registers) {
+		return 0
+	}
+	return em.registers[x]
+}
+
+func (em *emulator) SetRegister(x, v uint8) {
+	if int(x) >= len(em.registers) {
+		return
+	}
+	em.registers[x] = v
+}
+
+func (em *emulator) Memory(addr uint16) uint8 {
+	if !em.inMemory(addr, 1) {
+		return 0
+	}
+	return em.mem[addr]
+}
+
+func (em *emulator) SetMemory(addr uint16, v uint8) {
+	if !em.inMemory(addr, 1) {
+		return
+	}
+	em.mem[addr] = v
+}
+
+func (em *emulator) MemorySize() int {
+	return len(em.mem)
+}
+
+// Stack returns a copy of the call stack, only
+// levels below the stack pointer are in use
+func (em *emulator) Stack() []uint16 {
+	stack := make([]uint16, len(em.stack))
+	copy(stack, em.stack)
+	return stack
+}
+
+func (em *emulator) SetStack(level uint8, addr uint16) {
+	if int(level) >= len(em.stack) {
+		return
+	}
+	em.stack[level] = addr
+}
+
+func (em *emulator) SP() uint8 {
+	return em.sp
+}
+
+func (em *emulator) SetSP(sp uint8) {
+	if int(sp) > len(em.stack) {
+		return
+	}
+	em.sp = sp
+}
+
+func (em *emulator) I() uint16 {
+	return em.i
+}
+
+func (em *emulator) SetI(i uint16) {
+	em.i = i
+}
+
+func (em *emulator) PC() uint16 {
+	return em.pc
+}
+
+func (em *emulator) SetPC(pc uint16) {
+	em.pc = pc
+}
+
+func (em *emulator) DT() uint8 {
+	return em.dt
+}
+
+func (em *emulator) SetDT(dt uint8) {
+	em.dt = dt
+}
+
+func (em *emulator) ST() uint8 {
+	return em.st
+}
+
+func (em *emulator) SetST(st uint8) {
+	em.st = st
+}
+
+func (em *emulator) Display() display.Display {
+	return em.display
+}
+
+func (em *emulator) Keypad() keypad.Keypad {
+	return em.keypad
+}
+
+func (em *emulator) Speaker() speaker.Speaker {
+	return em.speaker
+}
@@ -0,0 +1,60 @@
+package emulator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Registers(t *testing.T) {
+	em := testEmulator()
+	em.SetRegister(3, 0x22)
+	registers := em.Registers()
+	assert.Equal(t, uint8(0x22), registers[3])
+
+	// modifying the copy leaves the machine untouched
+	registers[3] = 0x33
+	assert.Equal(t, uint8(0x22), em.Register(3))
+
+	// out of range registers read as zero and ignore writes
+	em.SetRegister(REGISTERS, 0x44)
+	assert.Equal(t, uint8(0), em.Register(REGISTERS))
+}
+
+func Test_Memory(t *testing.T) {
+	em := testEmulator()
+	em.SetMemory(0x300, 0xAB)
+	assert.Equal(t, uint8(0xAB), em.Memory(0x300))
+
+	em.SetMemory(MEM_SIZE, 0xCD)
+	assert.Equal(t, uint8(0), em.Memory(MEM_SIZE))
+	assert.Equal(t, uint8(0), em.Memory(0xFFFF))
+}
+
+func Test_Stack(t *testing.T) {
+	em := testEmulator()
+	em.SetStack(0, 0x234)
+	em.SetSP(1)
+	em.ret()
+	assert.Equal(t, uint16(0x234), em.PC())
+	assert.Equal(t, uint8(0), em.SP())
+	assert.Equal(t, uint16(0x234), em.Stack()[0])
+
+	em.SetStack(STACK_SIZE, 0x456)
+	assert.Len(t, em.Stack(), STACK_SIZE)
+
+	em.SetSP(STACK_SIZE + 4)
+	assert.Equal(t, uint8(0), em.SP())
+	em.SetSP(STACK_SIZE)
+	assert.Equal(t, uint8(STACK_SIZE), em.SP())
+}
+
+func Test_Timers(t *testing.T) {
+	em := testEmulator()
+	em.SetDT(4)
+	em.SetST(5)
+	em.SetI(0x300)
+	assert.Equal(t, uint8(4), em.DT())
+	assert.Equal(t, uint8(5), em.ST())
+	assert.Equal(t, uint16(0x300), em.I())
+}
@@ -0,0 +1,94 @@
+package emulator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Platform is a flavor of CHIP-8, each platform
+// supports every instruction of the ones before it
+type Platform int
+
+const (
+	PLATFORM_CHIP8 Platform = iota
+	PLATFORM_SCHIP
+	PLATFORM_XOCHIP
+)
+
+var platformNames = []string{
+	PLATFORM_CHIP8:  "chip8",
+	PLATFORM_SCHIP:  "schip",
+	PLATFORM_XOCHIP: "xochip",
+}
+
+func (p Platform) String() string {
+	if p < 0 || int(p) >= len(platformNames) {
+		return fmt.Sprintf("Platform(%d)", int(p))
+	}
+	return platformNames[p]
+}
+
+// ParsePlatform converts the name of a platform to its value
+func ParsePlatform(name string) (Platform, error) {
+	for p, pname := range platformNames {
+		if strings.EqualFold(name, pname) {
+			return Platform(p), nil
+		}
+	}
+	return PLATFORM_CHIP8, fmt.Errorf("unknown platform %q, expected one of %s", name, strings.Join(platformNames, ", "))
+}
+
+// Quirks returns the quirks preset roms written for the platform expect
+func (p Platform) Quirks() Quirks {
+	switch p {
+	case PLATFORM_SCHIP:
+		return quirkPresets["schip"]
+	case PLATFORM_XOCHIP:
+		return quirkPresets["xochip"]
+	}
+	return quirkPresets["modern"]
+}
+
+// MemorySize returns the amount of addressable memory
+func (p Platform) MemorySize() int {
+	if p == PLATFORM_XOCHIP {
+		return XO_MEM_SIZE
+	}
+	return MEM_SIZE
+}
+
+// flags returns the number of persistent flag registers
+func (p Platform) flags() int {
+	if p == PLATFORM_XOCHIP {
+		return XO_RPL_FLAGS
+	}
+	return RPL_FLAGS
+}
+
+// extension returns the first platform that introduced inst
+func extension(inst uint16) Platform {
+	switch {
+	case inst&^N4_MASK == SCU_N:
+		return PLATFORM_XOCHIP
+	case inst&N1_MASK == SEQ_VX_VY:
+		switch inst & N4_MASK {
+		case SAVE_VX_VY, LOAD_VX_VY:
+			return PLATFORM_XOCHIP
+		}
+	case inst&^N4_MASK == SCD_N,
+		inst == SCR,
+		inst == SCL,
+		inst == EXIT,
+		inst == LOW,
+		inst == HIGH:
+		return PLATFORM_SCHIP
+	case inst&N1_MASK == TIMING_OPS:
+		switch inst & (N3_MASK | N4_MASK) {
+		case LD_HF_VX, LD_R_VX, LD_VX_R:
+			return PLATFORM_SCHIP
+		case LD_I_LONG, PLANE_N, AUDIO, PITCH_VX:
+			return PLATFORM_XOCHIP
+		}
+	}
+	return PLATFORM_CHIP8
+}
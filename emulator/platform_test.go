@@ -0,0 +1,49 @@
+package emulator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParsePlatform(t *testing.T) {
+	p, err := ParsePlatform("SCHIP")
+	assert.Nil(t, err)
+	assert.Equal(t, PLATFORM_SCHIP, p)
+	assert.Equal(t, "schip", p.String())
+	assert.Equal(t, "Platform(7)", Platform(7).String())
+	_, err = ParsePlatform("chip-16")
+	assert.NotNil(t, err)
+}
+
+func Test_extension(t *testing.T) {
+	assert.Equal(t, PLATFORM_CHIP8, extension(0x00E0))
+	assert.Equal(t, PLATFORM_SCHIP, extension(0x00C4))
+	assert.Equal(t, PLATFORM_SCHIP, extension(0x00FF))
+	assert.Equal(t, PLATFORM_SCHIP, extension(0xF330))
+	assert.Equal(t, PLATFORM_CHIP8, extension(0xF329))
+	assert.Equal(t, PLATFORM_XOCHIP, extension(0x5122))
+	assert.Equal(t, PLATFORM_XOCHIP, extension(0xF000))
+	assert.Equal(t, PLATFORM_XOCHIP, extension(0xF201))
+	assert.Equal(t, PLATFORM_XOCHIP, extension(0x00D1))
+}
+
+func Test_MemorySize(t *testing.T) {
+	em := Create(&EmulatorSettings{Headless: true, Platform: PLATFORM_XOCHIP})
+	assert.Nil(t, em.LoadROM(make([]uint8, MEM_SIZE)))
+	em.SetMemory(XO_MEM_SIZE-1, 1)
+	assert.Equal(t, uint8(1), em.Memory(XO_MEM_SIZE-1))
+}
+
+func Test_execute_platform(t *testing.T) {
+	em := Create(&EmulatorSettings{Headless: true})
+	em.Load([]uint8{0x00, 0xFF})
+	assert.ErrorIs(t, em.Step(), ErrUnknownOpcode)
+
+	em = Create(&EmulatorSettings{Headless: true, Platform: PLATFORM_SCHIP})
+	em.Load([]uint8{0x00, 0xFF})
+	assert.Nil(t, em.Step())
+	rows, cols := em.Display().WindowSize()
+	assert.Equal(t, HIRES_ROWS, rows)
+	assert.Equal(t, HIRES_COLS, cols)
+}
@@ -0,0 +1,64 @@
+package emulator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Quirks toggles behavior that differs between CHIP-8 interpreters,
+// the zero value matches what most modern interpreters do
+type Quirks struct {
+	// 8XY6/8XYE shift vy into vx rather than shifting vx in place
+	Shift bool
+	// FX55/FX65 leave i pointing after the last register stored or loaded
+	MemoryIncrement bool
+	// BNNN jumps to nnn + vx rather than nnn + v0
+	Jump bool
+	// 8XY1/8XY2/8XY3 reset vf to zero
+	VFReset bool
+	// DXYN wraps sprites around the screen edges rather than clipping them
+	Wrap bool
+	// DXYN waits for the next frame, limiting drawing to once per frame
+	DisplayWait bool
+}
+
+var quirkPresets = map[string]Quirks{
+	"modern": {},
+	"cosmac": {
+		Shift:           true,
+		MemoryIncrement: true,
+		VFReset:         true,
+		DisplayWait:     true,
+	},
+	"chip48": {
+		Jump: true,
+	},
+	"schip": {
+		Jump: true,
+	},
+	"xochip": {
+		Shift:           true,
+		MemoryIncrement: true,
+		Wrap:            true,
+	},
+}
+
+// QuirksPreset returns the quirks of a well known interpreter by name
+func QuirksPreset(name string) (Quirks, error) {
+	quirks, ok := quirkPresets[strings.ToLower(name)]
+	if !ok {
+		return Quirks{}, fmt.Errorf("unknown quirks preset %q, expected one of %s", name, strings.Join(QuirksPresets(), ", "))
+	}
+	return quirks, nil
+}
+
+// QuirksPresets lists the names of all quirks presets
+func QuirksPresets() []string {
+	names := make([]string, 0, len(quirkPresets))
+	for name := range quirkPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
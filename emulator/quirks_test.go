@@ -0,0 +1,104 @@
+package emulator
+
+import (
+	"testing"
+
+	"github.com/bchadwic/chip8/internal/display/emit"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_QuirksPreset(t *testing.T) {
+	quirks, err := QuirksPreset("COSMAC")
+	assert.Nil(t, err)
+	assert.True(t, quirks.Shift)
+	assert.True(t, quirks.DisplayWait)
+
+	quirks, err = QuirksPreset("modern")
+	assert.Nil(t, err)
+	assert.Equal(t, Quirks{}, quirks)
+
+	_, err = QuirksPreset("pdp-11")
+	assert.NotNil(t, err)
+}
+
+func Test_QuirksPreset_xochip(t *testing.T) {
+	quirks, err := QuirksPreset("xochip")
+	assert.Nil(t, err)
+	assert.True(t, quirks.Shift)
+	assert.True(t, quirks.MemoryIncrement)
+	assert.False(t, quirks.Jump)
+	assert.False(t, quirks.VFReset)
+	assert.True(t, quirks.Wrap)
+	assert.False(t, quirks.DisplayWait)
+	assert.Equal(t, quirks, PLATFORM_XOCHIP.Quirks())
+}
+
+func Test_quirks_shift(t *testing.T) {
+	em := testEmulator()
+	em.settings.Quirks.Shift = true
+	em.registers[3] = 0x01
+	em.registers[4] = 0x82
+	em.shrVxVy(3, 4)
+	assert.Equal(t, uint8(0x41), em.registers[3])
+	assert.Equal(t, uint8(0), em.registers[0xF])
+	em.shlVxVy(3, 4)
+	assert.Equal(t, uint8(0x04), em.registers[3])
+	assert.Equal(t, uint8(1), em.registers[0xF])
+}
+
+func Test_quirks_memoryIncrement(t *testing.T) {
+	em := testEmulator()
+	em.settings.Quirks.MemoryIncrement = true
+	em.i = 0x300
+	em.ldIVx(3)
+	assert.Equal(t, uint16(0x304), em.i)
+	em.ldVxI(1)
+	assert.Equal(t, uint16(0x306), em.i)
+}
+
+func Test_quirks_jump(t *testing.T) {
+	em := testEmulator()
+	em.settings.Quirks.Jump = true
+	em.registers[0] = 1
+	em.registers[2] = 4
+	em.jmpV0(0x230)
+	assert.Equal(t, uint16(0x234), em.pc)
+}
+
+func Test_quirks_vfReset(t *testing.T) {
+	em := testEmulator()
+	em.settings.Quirks.VFReset = true
+	em.registers[0xF] = 1
+	em.orVxVy(3, 4)
+	assert.Equal(t, uint8(0), em.registers[0xF])
+}
+
+func Test_quirks_wrap(t *testing.T) {
+	em := Create(&EmulatorSettings{Headless: true}).(*emulator)
+	em.i = 0x300
+	em.mem[0x300] = 0xFF
+	em.registers[0] = COLS - 4
+	em.drawVxVyN(0, 1, 1)
+	assert.Equal(t, emit.OFF, em.display.Get(0, 0))
+
+	em.settings.Quirks.Wrap = true
+	em.display.Clear()
+	em.drawVxVyN(0, 1, 1)
+	assert.Equal(t, emit.ON, em.display.Get(0, 0))
+	assert.Equal(t, emit.ON, em.display.Get(0, 3))
+	assert.Equal(t, emit.OFF, em.display.Get(0, 4))
+}
+
+func Test_quirks_displayWait(t *testing.T) {
+	em := Create(&EmulatorSettings{
+		Headless:              true,
+		InstructionsPerSecond: 600,
+		Quirks:                Quirks{DisplayWait: true},
+	})
+	// draw, then add one to v2 and jump back
+	em.Load([]uint8{0xD0, 0x01, 0x72, 0x01, 0x12, 0x00})
+	assert.Nil(t, em.RunFrame())
+	assert.Equal(t, uint8(0), em.Register(2))
+	assert.Nil(t, em.RunFrame())
+	assert.Equal(t, uint8(1), em.Register(2))
+}
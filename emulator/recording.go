@@ -0,0 +1,64 @@
+package emulator
+
+import (
+	"errors"
+	"log"
+
+	"github.com/bchadwic/chip8/internal/display"
+	"github.com/bchadwic/chip8/internal/video"
+)
+
+// StartRecording records every frame from now on to a file at path, a
+// gif or y4m video depending on its extension, replacing any recording
+// in progress
+func (em *emulator) StartRecording(path string) error {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	return em.startRecording(path)
+}
+
+// StopRecording finishes the recording in progress
+func (em *emulator) StopRecording() error {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	return em.stopRecording()
+}
+
+func (em *emulator) startRecording(path string) error {
+	if err := em.stopRecording(); err != nil {
+		return err
+	}
+	r, err := video.Create(path, em.captureScale(), display.Palette(em.settings.Color))
+	if err != nil {
+		return err
+	}
+	em.recording = r
+	return nil
+}
+
+func (em *emulator) stopRecording() error {
+	if em.recording == nil {
+		return nil
+	}
+	err := em.recording.Close()
+	em.recording = nil
+	return err
+}
+
+// toggleRecording starts a gif named after RecordingPath
+// and the current time, or stops the one in progress
+func (em *emulator) toggleRecording() error {
+	if em.recording != nil {
+		log.Print("stopped recording")
+		return em.stopRecording()
+	}
+	if em.settings.RecordingPath == "" {
+		return errors.New("no recording file set")
+	}
+	path := em.settings.RecordingPath + timestamp() + ".gif"
+	if err := em.startRecording(path); err != nil {
+		return err
+	}
+	log.Printf("recording to %s", path)
+	return nil
+}
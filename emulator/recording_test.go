@@ -0,0 +1,52 @@
+package emulator
+
+import (
+	"image/gif"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bchadwic/chip8/internal/hotkey"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_StartRecording(t *testing.T) {
+	rom, err := os.ReadFile("../roms/ibm.ch8")
+	assert.Nil(t, err)
+	path := filepath.Join(t.TempDir(), "ibm.gif")
+
+	em := Create(&EmulatorSettings{Headless: true, ScreenshotScale: 1})
+	assert.Nil(t, em.LoadROM(rom))
+	assert.Nil(t, em.StartRecording(path))
+	for f := 0; f < 10; f++ {
+		assert.Nil(t, em.RunFrame())
+	}
+	assert.Nil(t, em.StopRecording())
+
+	f, err := os.Open(path)
+	assert.Nil(t, err)
+	defer f.Close()
+	anim, err := gif.DecodeAll(f)
+	assert.Nil(t, err)
+	assert.Equal(t, COLS, anim.Config.Width)
+	assert.NotEmpty(t, anim.Image)
+}
+
+func Test_handleHotkeys_record(t *testing.T) {
+	rom, err := os.ReadFile("../roms/ibm.ch8")
+	assert.Nil(t, err)
+	dir := t.TempDir()
+	em := Create(&EmulatorSettings{Headless: true, RecordingPath: filepath.Join(dir, "ibm")}).(*emulator)
+	assert.Nil(t, em.LoadROM(rom))
+	em.hotkeys <- hotkey.RECORD
+	em.handleHotkeys()
+	assert.NotNil(t, em.recording)
+	assert.Nil(t, em.RunFrame())
+
+	em.hotkeys <- hotkey.RECORD
+	em.handleHotkeys()
+	assert.Nil(t, em.recording)
+	clips, err := filepath.Glob(filepath.Join(dir, "ibm-*.gif"))
+	assert.Nil(t, err)
+	assert.Len(t, clips, 1)
+}
@@ -0,0 +1,102 @@
+package emulator
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// rewinder keeps the most recent save states for stepping backwards.
+// Only the newest state is kept whole, every older state is stored as
+// the run length encoded xor between it and the state that followed,
+// which is mostly zeroes since little changes within a frame.
+type rewinder struct {
+	// newest state
+	state []byte
+	// ring of deltas, oldest first starting at start
+	deltas     [][]byte
+	start, len int
+}
+
+func newRewinder(frames int) *rewinder {
+	return &rewinder{deltas: make([][]byte, frames)}
+}
+
+// push records the next state
+func (r *rewinder) push(state []byte) {
+	if r.state != nil && len(r.deltas) > 0 {
+		if r.len == len(r.deltas) {
+			// the oldest state falls off the end
+			r.start = (r.start + 1) % len(r.deltas)
+			r.len--
+		}
+		r.deltas[(r.start+r.len)%len(r.deltas)] = delta(state, r.state)
+		r.len++
+	}
+	r.state = state
+}
+
+// pop discards the newest state, returning the one before it
+func (r *rewinder) pop() ([]byte, bool) {
+	if r.len == 0 {
+		return nil, false
+	}
+	r.len--
+	i := (r.start + r.len) % len(r.deltas)
+	r.state = apply(r.state, r.deltas[i])
+	r.deltas[i] = nil
+	return r.state, true
+}
+
+// frames returns how many states can be popped
+func (r *rewinder) frames() int {
+	return r.len
+}
+
+// delta encodes the xor of from and to, so that applying it to
+// from gives back to. It is the length of to followed by pairs
+// of a run of unchanged bytes and a run of changed bytes.
+func delta(from, to []byte) []byte {
+	out := binary.AppendUvarint(nil, uint64(len(to)))
+	for i := 0; i < len(to); {
+		same := i
+		for same < len(to) && same < len(from) && from[same] == to[same] {
+			same++
+		}
+		diff := same
+		for diff < len(to) && (diff >= len(from) || from[diff] != to[diff]) {
+			diff++
+		}
+		out = binary.AppendUvarint(out, uint64(same-i))
+		out = binary.AppendUvarint(out, uint64(diff-same))
+		for j := same; j < diff; j++ {
+			out = append(out, to[j]^at(from, j))
+		}
+		i = diff
+	}
+	return out
+}
+
+// apply reverses delta, returning to given from
+func apply(from, d []byte) []byte {
+	r := bytes.NewReader(d)
+	n, _ := binary.ReadUvarint(r)
+	to := make([]byte, n)
+	copy(to, from)
+	for i := 0; r.Len() > 0; {
+		same, _ := binary.ReadUvarint(r)
+		diff, _ := binary.ReadUvarint(r)
+		i += int(same)
+		for end := i + int(diff); i < end; i++ {
+			b, _ := r.ReadByte()
+			to[i] = b ^ at(from, i)
+		}
+	}
+	return to
+}
+
+func at(b []byte, i int) byte {
+	if i < len(b) {
+		return b[i]
+	}
+	return 0
+}
@@ -0,0 +1,65 @@
+package emulator
+
+import (
+	"testing"
+
+	"github.com/bchadwic/chip8/internal/hotkey"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_rewinder(t *testing.T) {
+	r := newRewinder(2)
+	r.push([]byte{1, 2, 3})
+	r.push([]byte{1, 5, 3, 4})
+	r.push([]byte{9, 5})
+	r.push([]byte{9, 6})
+	assert.Equal(t, 2, r.frames())
+
+	state, ok := r.pop()
+	assert.True(t, ok)
+	assert.Equal(t, []byte{9, 5}, state)
+	state, ok = r.pop()
+	assert.True(t, ok)
+	assert.Equal(t, []byte{1, 5, 3, 4}, state)
+	// the first state fell out of the ring
+	_, ok = r.pop()
+	assert.False(t, ok)
+
+	r.push([]byte{7, 7, 7, 7})
+	state, _ = r.pop()
+	assert.Equal(t, []byte{1, 5, 3, 4}, state)
+}
+
+func Test_delta(t *testing.T) {
+	from := make([]byte, 4096)
+	to := make([]byte, 4096)
+	to[100], to[3000] = 1, 2
+	d := delta(from, to)
+	assert.Less(t, len(d), 16)
+	assert.Equal(t, to, apply(from, d))
+}
+
+func Test_Rewind(t *testing.T) {
+	em := testStateMachine(t, &EmulatorSettings{RewindFrames: 10})
+	assert.False(t, em.Rewind())
+
+	assert.Nil(t, em.Run(1))
+	pc := em.PC()
+	assert.Nil(t, em.Run(1))
+	assert.NotEqual(t, pc, em.PC())
+
+	assert.True(t, em.Rewind())
+	assert.Equal(t, pc, em.PC())
+	assert.True(t, em.Rewind())
+	assert.Equal(t, uint16(ROM_ADDR), em.PC())
+	assert.False(t, em.Rewind())
+}
+
+func Test_handleHotkeys_rewind(t *testing.T) {
+	em := testStateMachine(t, &EmulatorSettings{}).(*emulator)
+	em.hotkeys <- hotkey.REWIND
+	assert.True(t, em.handleHotkeys())
+	// held over for a frame without the key
+	assert.True(t, em.handleHotkeys())
+	assert.False(t, em.handleHotkeys())
+}
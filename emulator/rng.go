@@ -0,0 +1,29 @@
+package emulator
+
+// rng is a xorshift64* generator, unlike math/rand its
+// whole state is a single number that can be saved
+// and restored along with the rest of the machine
+type rng struct {
+	state uint64
+}
+
+// newRNG seeds a generator, spreading the bits of small seeds with
+// splitmix64 since xorshift never leaves a state of zero
+func newRNG(seed uint64) *rng {
+	z := seed + 0x9E3779B97F4A7C15
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	z ^= z >> 31
+	if z == 0 {
+		z = 1
+	}
+	return &rng{state: z}
+}
+
+// next returns a random byte
+func (r *rng) next() uint8 {
+	r.state ^= r.state >> 12
+	r.state ^= r.state << 25
+	r.state ^= r.state >> 27
+	return uint8((r.state * 0x2545F4914F6CDD1D) >> 56)
+}
@@ -0,0 +1,41 @@
+package emulator
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/bchadwic/chip8/internal/display"
+)
+
+// Screenshot writes the screen to a png file at path, drawn
+// with the pixel color and ScreenshotScale of the settings
+func (em *emulator) Screenshot(path string) error {
+	return display.Screenshot(em.display, path, em.captureScale(), display.Palette(em.settings.Color))
+}
+
+// screenshotFile takes a screenshot named after ScreenshotPath and the current time
+func (em *emulator) screenshotFile() error {
+	if em.settings.ScreenshotPath == "" {
+		return errors.New("no screenshot file set")
+	}
+	path := em.settings.ScreenshotPath + timestamp() + ".png"
+	if err := em.Screenshot(path); err != nil {
+		return err
+	}
+	log.Printf("saved screenshot %s", path)
+	return nil
+}
+
+// captureScale is the width of a pixel in screenshots and recordings
+func (em *emulator) captureScale() int {
+	if em.settings.ScreenshotScale <= 0 {
+		return display.SCALE
+	}
+	return em.settings.ScreenshotScale
+}
+
+// timestamp names files taken with hotkeys apart
+func timestamp() string {
+	return time.Now().Format("-20060102-150405.000")
+}
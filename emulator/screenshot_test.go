@@ -0,0 +1,40 @@
+package emulator
+
+import (
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bchadwic/chip8/internal/display/emit"
+	"github.com/bchadwic/chip8/internal/hotkey"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Screenshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shot.png")
+	em := Create(&EmulatorSettings{Headless: true, Color: "red", ScreenshotScale: 2})
+	em.Display().Set(emit.ON, 1, 3)
+	assert.Nil(t, em.Screenshot(path))
+
+	f, err := os.Open(path)
+	assert.Nil(t, err)
+	defer f.Close()
+	img, err := png.Decode(f)
+	assert.Nil(t, err)
+	assert.Equal(t, COLS*2, img.Bounds().Dx())
+	assert.Equal(t, ROWS*2, img.Bounds().Dy())
+	assert.Equal(t, color.RGBA{0xFF, 0x00, 0x00, 0xFF}, color.RGBAModel.Convert(img.At(7, 3)))
+	assert.Equal(t, color.RGBA{0x00, 0x00, 0x00, 0xFF}, color.RGBAModel.Convert(img.At(8, 3)))
+}
+
+func Test_handleHotkeys_screenshot(t *testing.T) {
+	dir := t.TempDir()
+	em := Create(&EmulatorSettings{Headless: true, ScreenshotPath: filepath.Join(dir, "ibm")}).(*emulator)
+	em.hotkeys <- hotkey.SCREENSHOT
+	em.handleHotkeys()
+	shots, err := filepath.Glob(filepath.Join(dir, "ibm-*.png"))
+	assert.Nil(t, err)
+	assert.Len(t, shots, 1)
+}
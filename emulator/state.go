@@ -0,0 +1,244 @@
+package emulator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bchadwic/chip8/internal/display"
+	"github.com/bchadwic/chip8/internal/display/emit"
+	"github.com/bchadwic/chip8/internal/speaker"
+)
+
+const (
+	STATE_MAGIC = "CH8S"
+	// bumped whenever the layout of a save state changes
+	STATE_VERSION = 2
+)
+
+var (
+	ErrStateFormat   = errors.New("not a save state")
+	ErrStateVersion  = errors.New("unsupported save state version")
+	ErrStateROM      = errors.New("save state is for a different rom")
+	ErrStatePlatform = errors.New("save state is for a different platform")
+)
+
+// stateHeader starts every save state, identifying the rom it was taken from
+type stateHeader struct {
+	Magic   [4]byte
+	Version uint16
+	ROM     [sha256.Size]byte
+}
+
+// stateMachine is the fixed size part of a save state, followed by
+// memory and then the screen with one bit per pixel per plane
+type stateMachine struct {
+	Platform  uint8
+	Registers [REGISTERS]uint8
+	I, PC     uint16
+	SP        uint8
+	Stack     [STACK_SIZE]uint16
+	DT, ST    uint8
+	Cycles    uint64
+	Carry     uint32
+	Halted    bool
+	RPL       [XO_RPL_FLAGS]uint8
+	Planes    uint8
+	Keypad    uint16
+	Random    uint64
+
+	// audio pattern, only used when HasPattern is set
+	HasPattern bool
+	Pattern    [speaker.PATTERN_SIZE]uint8
+	Pitch      uint8
+
+	Rows, Cols    uint8
+	DisplayPlanes uint8
+	MemSize       uint32
+}
+
+// SaveState writes the machine state to w. The machine
+// should be paused while the state is being saved.
+func (em *emulator) SaveState(w io.Writer) error {
+	header := stateHeader{Version: STATE_VERSION, ROM: sha256.Sum256(em.rom)}
+	copy(header.Magic[:], STATE_MAGIC)
+
+	screen := em.display.Snapshot()
+	pattern, pitch := em.speaker.Pattern()
+	state := stateMachine{
+		Platform:      uint8(em.settings.Platform),
+		I:             em.i,
+		PC:            em.pc,
+		SP:            em.sp,
+		DT:            em.dt,
+		ST:            em.st,
+		Cycles:        uint64(em.cycles),
+		Carry:         uint32(em.carry),
+		Halted:        em.halted,
+		Planes:        em.planes,
+		Keypad:        em.keypad.State(),
+		Random:        em.rng.state,
+		HasPattern:    pattern != nil,
+		Pitch:         pitch,
+		Rows:          screen.Rows,
+		Cols:          screen.Cols,
+		DisplayPlanes: screen.Planes,
+		MemSize:       uint32(len(em.mem)),
+	}
+	copy(state.Registers[:], em.registers)
+	copy(state.Stack[:], em.stack)
+	copy(state.RPL[:], em.rpl)
+	copy(state.Pattern[:], pattern)
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, header)
+	binary.Write(&buf, binary.BigEndian, state)
+	buf.Write(em.mem)
+	buf.Write(packScreen(screen.Screen))
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// LoadState restores a state written by SaveState, the state must
+// have been saved from the same rom and platform. The machine is
+// left untouched if the state can not be loaded. The machine should
+// be paused while the state is being loaded.
+func (em *emulator) LoadState(r io.Reader) error {
+	var header stateHeader
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil || string(header.Magic[:]) != STATE_MAGIC {
+		return ErrStateFormat
+	}
+	if header.Version != STATE_VERSION {
+		return fmt.Errorf("%w: %d", ErrStateVersion, header.Version)
+	}
+	if header.ROM != sha256.Sum256(em.rom) {
+		return ErrStateROM
+	}
+
+	var state stateMachine
+	if err := binary.Read(r, binary.BigEndian, &state); err != nil {
+		return fmt.Errorf("%w: %v", ErrStateFormat, err)
+	}
+	if Platform(state.Platform) != em.settings.Platform {
+		return fmt.Errorf("%w: %v", ErrStatePlatform, Platform(state.Platform))
+	}
+	if err := state.check(em.settings.Platform, len(em.mem)); err != nil {
+		return err
+	}
+	mem := make([]uint8, state.MemSize)
+	packed := make([]uint8, (display.PLANES*int(state.Rows)*int(state.Cols)+7)/8)
+	if _, err := io.ReadFull(r, mem); err != nil {
+		return fmt.Errorf("%w: %v", ErrStateFormat, err)
+	}
+	if _, err := io.ReadFull(r, packed); err != nil {
+		return fmt.Errorf("%w: %v", ErrStateFormat, err)
+	}
+
+	copy(em.registers, state.Registers[:])
+	em.mem = mem
+	em.i = state.I
+	em.pc = state.PC
+	em.sp = state.SP
+	copy(em.stack, state.Stack[:])
+	em.dt = state.DT
+	em.st = state.ST
+	em.cycles = int(state.Cycles)
+	em.carry = int(state.Carry)
+	em.halted = state.Halted
+	em.vblank = false
+	em.waiting = false
+	em.awaiting = false
+	copy(em.rpl, state.RPL[:])
+	em.planes = state.Planes
+	em.keypad.Restore(state.Keypad)
+	em.rng.state = state.Random
+	if state.HasPattern {
+		em.speaker.SetPattern(state.Pattern[:])
+	} else {
+		em.speaker.SetPattern(nil)
+	}
+	em.speaker.SetPitch(state.Pitch)
+	em.display.Restore(display.State{
+		Rows:   state.Rows,
+		Cols:   state.Cols,
+		Planes: state.DisplayPlanes,
+		Screen: unpackScreen(packed, display.PLANES*int(state.Rows)*int(state.Cols)),
+	})
+	return nil
+}
+
+// check reports states that could not have been saved by a machine
+// on platform p, such as corrupted or edited ones
+func (s *stateMachine) check(p Platform, memSize int) error {
+	if int(s.MemSize) != memSize {
+		return fmt.Errorf("%w: memory is %d bytes, want %d", ErrStateFormat, s.MemSize, memSize)
+	}
+	if int(s.SP) > STACK_SIZE {
+		return fmt.Errorf("%w: stack pointer %d is past the stack", ErrStateFormat, s.SP)
+	}
+	lowres := s.Rows == ROWS && s.Cols == COLS
+	hires := s.Rows == HIRES_ROWS && s.Cols == HIRES_COLS && p >= PLATFORM_SCHIP
+	if !lowres && !hires {
+		return fmt.Errorf("%w: display is %dx%d", ErrStateFormat, s.Cols, s.Rows)
+	}
+	// only xo-chip can select planes, and it may select none
+	planes := uint8(1)
+	if p >= PLATFORM_XOCHIP {
+		planes = 1<<display.PLANES - 1
+	}
+	valid := func(mask uint8) bool {
+		return mask&^planes == 0 && (p >= PLATFORM_XOCHIP || mask != 0)
+	}
+	if !valid(s.Planes) || !valid(s.DisplayPlanes) {
+		return fmt.Errorf("%w: planes 0x%X and 0x%X are not on %v", ErrStateFormat, s.Planes, s.DisplayPlanes, p)
+	}
+	return nil
+}
+
+// saveStateFile saves the machine state to StatePath
+func (em *emulator) saveStateFile() error {
+	if em.settings.StatePath == "" {
+		return errors.New("no save state file set")
+	}
+	var buf bytes.Buffer
+	if err := em.SaveState(&buf); err != nil {
+		return err
+	}
+	return os.WriteFile(em.settings.StatePath, buf.Bytes(), 0644)
+}
+
+// loadStateFile loads the machine state from StatePath
+func (em *emulator) loadStateFile() error {
+	if em.settings.StatePath == "" {
+		return errors.New("no save state file set")
+	}
+	f, err := os.Open(em.settings.StatePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return em.LoadState(f)
+}
+
+// packScreen stores a pixel per bit
+func packScreen(screen []emit.Emit) []uint8 {
+	packed := make([]uint8, (len(screen)+7)/8)
+	for i, e := range screen {
+		if e == emit.ON {
+			packed[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return packed
+}
+
+func unpackScreen(packed []uint8, n int) []emit.Emit {
+	screen := make([]emit.Emit, n)
+	for i := range screen {
+		screen[i] = emit.Emit(packed[i/8]&(0x80>>(i%8)) != 0)
+	}
+	return screen
+}
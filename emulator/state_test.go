@@ -0,0 +1,191 @@
+package emulator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bchadwic/chip8/internal/hotkey"
+	"github.com/bchadwic/chip8/internal/keypad"
+	"github.com/stretchr/testify/assert"
+)
+
+func testStateMachine(t *testing.T, settings *EmulatorSettings) Machine {
+	rom, err := os.ReadFile("../roms/ibm.ch8")
+	assert.Nil(t, err)
+	settings.Headless = true
+	em := Create(settings)
+	assert.Nil(t, em.LoadROM(rom))
+	return em
+}
+
+func Test_SaveState(t *testing.T) {
+	em := testStateMachine(t, &EmulatorSettings{})
+	assert.Nil(t, em.Run(10))
+	em.SetRegister(3, 0x33)
+	em.SetStack(2, 0x456)
+	em.SetSP(3)
+	em.SetDT(7)
+	em.Keypad().Press(0xA)
+	em.Keypad().(keypad.Framer).Frame()
+
+	var buf bytes.Buffer
+	assert.Nil(t, em.SaveState(&buf))
+	saved := buf.Bytes()
+	pixels := em.Display().Pixels()
+	pc := em.PC()
+
+	em.Reset()
+	assert.NotEqual(t, pixels, em.Display().Pixels())
+	assert.Nil(t, em.LoadState(bytes.NewReader(saved)))
+	assert.Equal(t, pc, em.PC())
+	assert.Equal(t, uint8(0x33), em.Register(3))
+	assert.Equal(t, uint16(0x456), em.Stack()[2])
+	assert.Equal(t, uint8(3), em.SP())
+	assert.Equal(t, uint8(7), em.DT())
+	assert.True(t, em.Keypad().Get(0xA))
+	assert.Equal(t, pixels, em.Display().Pixels())
+
+	// saving the restored machine gives the same state
+	buf.Reset()
+	assert.Nil(t, em.SaveState(&buf))
+	assert.Equal(t, saved, buf.Bytes())
+}
+
+func Test_LoadState_errors(t *testing.T) {
+	em := testStateMachine(t, &EmulatorSettings{})
+	var buf bytes.Buffer
+	assert.Nil(t, em.SaveState(&buf))
+	saved := buf.Bytes()
+
+	err := em.LoadState(bytes.NewReader([]byte("nope")))
+	assert.True(t, errors.Is(err, ErrStateFormat))
+
+	version := append([]byte{}, saved...)
+	version[5] = STATE_VERSION + 1
+	err = em.LoadState(bytes.NewReader(version))
+	assert.True(t, errors.Is(err, ErrStateVersion))
+
+	err = em.LoadState(bytes.NewReader(saved[:len(saved)-1]))
+	assert.True(t, errors.Is(err, ErrStateFormat))
+
+	other := Create(&EmulatorSettings{Headless: true})
+	other.Load([]uint8{0x12, 0x00})
+	err = other.LoadState(bytes.NewReader(saved))
+	assert.True(t, errors.Is(err, ErrStateROM))
+
+	xo := testStateMachine(t, &EmulatorSettings{Platform: PLATFORM_XOCHIP})
+	err = xo.LoadState(bytes.NewReader(saved))
+	assert.True(t, errors.Is(err, ErrStatePlatform))
+}
+
+// corrupt rewrites the fixed size part of a saved state with edit
+func corrupt(t *testing.T, saved []byte, edit func(s *stateMachine)) []byte {
+	at := binary.Size(stateHeader{})
+	var state stateMachine
+	assert.Nil(t, binary.Read(bytes.NewReader(saved[at:]), binary.BigEndian, &state))
+	edit(&state)
+	var buf bytes.Buffer
+	buf.Write(saved[:at])
+	binary.Write(&buf, binary.BigEndian, state)
+	buf.Write(saved[at+binary.Size(state):])
+	return buf.Bytes()
+}
+
+func Test_LoadState_corrupt(t *testing.T) {
+	em := testStateMachine(t, &EmulatorSettings{})
+	assert.Nil(t, em.Run(5))
+	var buf bytes.Buffer
+	assert.Nil(t, em.SaveState(&buf))
+	saved := buf.Bytes()
+	pc := em.PC()
+
+	for name, edit := range map[string]func(s *stateMachine){
+		"no planes":     func(s *stateMachine) { s.DisplayPlanes = 0 },
+		"second plane":  func(s *stateMachine) { s.Planes = 2 },
+		"odd size":      func(s *stateMachine) { s.Rows, s.Cols = 16, 16 },
+		"hires on chip": func(s *stateMachine) { s.Rows, s.Cols = HIRES_ROWS, HIRES_COLS },
+		"stack":         func(s *stateMachine) { s.SP = STACK_SIZE + 1 },
+	} {
+		err := em.LoadState(bytes.NewReader(corrupt(t, saved, edit)))
+		assert.ErrorIs(t, err, ErrStateFormat, name)
+		assert.Equal(t, pc, em.PC(), name)
+	}
+	// untouched states still load
+	assert.Nil(t, em.LoadState(bytes.NewReader(corrupt(t, saved, func(s *stateMachine) {}))))
+}
+
+func Test_handleHotkeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ibm.state")
+	em := testStateMachine(t, &EmulatorSettings{StatePath: path}).(*emulator)
+	em.SetRegister(1, 0x11)
+	em.hotkeys <- hotkey.SAVE_STATE
+	em.handleHotkeys()
+	_, err := os.Stat(path)
+	assert.Nil(t, err)
+
+	em.SetRegister(1, 0x22)
+	em.hotkeys <- hotkey.LOAD_STATE
+	em.handleHotkeys()
+	assert.Equal(t, uint8(0x11), em.Register(1))
+}
+
+func Test_handleHotkeys_paused(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ibm.state")
+	em := testStateMachine(t, &EmulatorSettings{StatePath: path, RewindFrames: 10}).(*emulator)
+	assert.Nil(t, em.Run(5))
+	pc := em.PC()
+	em.Pause()
+	for _, h := range []hotkey.Hotkey{hotkey.SAVE_STATE, hotkey.LOAD_STATE, hotkey.REWIND} {
+		em.hotkeys <- h
+	}
+	assert.False(t, em.handleHotkeys())
+	_, err := os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Equal(t, pc, em.PC())
+}
+
+func Test_handleHotkeys_movie(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ibm.state")
+	em := testStateMachine(t, &EmulatorSettings{StatePath: path, RewindFrames: 10, Movie: true}).(*emulator)
+	// saving is still fine, only loading changes the machine
+	em.hotkeys <- hotkey.SAVE_STATE
+	assert.False(t, em.handleHotkeys())
+	assert.FileExists(t, path)
+	assert.Nil(t, em.Run(5))
+	pc := em.PC()
+	em.hotkeys <- hotkey.LOAD_STATE
+	em.hotkeys <- hotkey.REWIND
+	assert.False(t, em.handleHotkeys())
+	assert.Equal(t, pc, em.PC())
+}
+
+func Test_SaveState_random(t *testing.T) {
+	em := testStateMachine(t, &EmulatorSettings{Seed: 7})
+	assert.Equal(t, uint64(7), em.Seed())
+
+	var buf bytes.Buffer
+	assert.Nil(t, em.SaveState(&buf))
+	saved := buf.Bytes()
+	em.SetMemory(ROM_ADDR, 0xC0)
+	em.SetMemory(ROM_ADDR+1, 0xFF)
+	assert.Nil(t, em.Step())
+	r := em.Register(0)
+
+	// the restored machine draws the same random number
+	assert.Nil(t, em.LoadState(bytes.NewReader(saved)))
+	em.SetMemory(ROM_ADDR, 0xC0)
+	em.SetMemory(ROM_ADDR+1, 0xFF)
+	assert.Nil(t, em.Step())
+	assert.Equal(t, r, em.Register(0))
+
+	// as does another machine with the same seed
+	other := testStateMachine(t, &EmulatorSettings{Seed: 7})
+	other.SetMemory(ROM_ADDR, 0xC0)
+	other.SetMemory(ROM_ADDR+1, 0xFF)
+	assert.Nil(t, other.Step())
+	assert.Equal(t, r, other.Register(0))
+}
@@ -0,0 +1,158 @@
+package emulator
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Trace writes every executed instruction along with the
+// registers it changed, which is handy for comparing runs
+// against other interpreters
+type Trace struct {
+	W io.Writer
+	// addresses traced, everything is traced when empty
+	Ranges []AddrRange
+	// opcode classes traced as a bitmask of first nibbles,
+	// bit 0xD traces DXYN, everything is traced when zero
+	Classes uint16
+	// instructions traced before tracing stops, 0 for no limit
+	Max int
+
+	count int
+}
+
+// AddrRange is an inclusive range of addresses
+type AddrRange struct {
+	Lo, Hi uint16
+}
+
+// cpu is the part of the machine a trace reports changes to
+type cpu struct {
+	registers [REGISTERS]uint8
+	i, pc     uint16
+	sp        uint8
+	dt, st    uint8
+}
+
+func (em *emulator) cpu() cpu {
+	c := cpu{i: em.i, pc: em.pc, sp: em.sp, dt: em.dt, st: em.st}
+	copy(c.registers[:], em.registers)
+	return c
+}
+
+// traces reports whether the instruction at pc should be traced
+func (t *Trace) traces(pc uint16, inst uint16) bool {
+	if t.Max > 0 && t.count >= t.Max {
+		return false
+	}
+	if t.Classes != 0 && t.Classes&(1<<(inst>>12)) == 0 {
+		return false
+	}
+	if len(t.Ranges) == 0 {
+		return true
+	}
+	for _, r := range t.Ranges {
+		if pc >= r.Lo && pc <= r.Hi {
+			return true
+		}
+	}
+	return false
+}
+
+// write traces a single instruction, given the cpu before and after it ran
+func (t *Trace) write(em *emulator, before, after cpu, inst uint16, err error) {
+	t.count++
+	code := []uint8{uint8(inst >> 8), uint8(inst)}
+	if em.inMemory(before.pc, 4) {
+		code = em.mem[before.pc : before.pc+4]
+	}
+	mnemonic, size := Disassemble(code)
+
+	var changes []string
+	for x := range before.registers {
+		if before.registers[x] != after.registers[x] {
+			changes = append(changes, fmt.Sprintf("V%X=%02X", x, after.registers[x]))
+		}
+	}
+	if before.i != after.i {
+		changes = append(changes, fmt.Sprintf("I=%03X", after.i))
+	}
+	if before.sp != after.sp {
+		changes = append(changes, fmt.Sprintf("SP=%d", after.sp))
+	}
+	if before.dt != after.dt {
+		changes = append(changes, fmt.Sprintf("DT=%02X", after.dt))
+	}
+	if before.st != after.st {
+		changes = append(changes, fmt.Sprintf("ST=%02X", after.st))
+	}
+	// only jumps are worth pointing out
+	if err != nil {
+		changes = append(changes, "fault: "+err.Error())
+	} else if after.pc != before.pc+uint16(size) {
+		changes = append(changes, fmt.Sprintf("PC=%03X", after.pc))
+	}
+	line := fmt.Sprintf("%03X  %-9X  %-22s %s", before.pc, code[:size], mnemonic, strings.Join(changes, " "))
+	fmt.Fprintln(t.W, strings.TrimRight(line, " "))
+}
+
+// ParseAddrRanges parses a comma separated list of addresses
+// and ranges of addresses such as 0x200-0x2FF,0x300
+func ParseAddrRanges(s string) ([]AddrRange, error) {
+	var ranges []AddrRange
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		r := AddrRange{}
+		var err error
+		if r.Lo, err = parseAddr(lo); err != nil {
+			return nil, err
+		}
+		r.Hi = r.Lo
+		if isRange {
+			if r.Hi, err = parseAddr(hi); err != nil {
+				return nil, err
+			}
+		}
+		if r.Hi < r.Lo {
+			return nil, fmt.Errorf("invalid address range: %s", part)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+func parseAddr(s string) (uint16, error) {
+	v, err := strconv.ParseUint(strings.TrimSpace(s), 0, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address: %s", s)
+	}
+	return uint16(v), nil
+}
+
+// ParseOpcodeClasses parses a comma separated list of opcode classes
+// named by their first nibble, like D or Dxxx, into a Trace.Classes mask
+func ParseOpcodeClasses(s string) (uint16, error) {
+	var classes uint16
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		nibble := strings.TrimRight(strings.ToLower(part), "x")
+		if len(part) != 1 && len(part) != 4 || len(nibble) != 1 {
+			return 0, fmt.Errorf("invalid opcode class: %s", part)
+		}
+		n, err := strconv.ParseUint(nibble, 16, 4)
+		if err != nil {
+			return 0, fmt.Errorf("invalid opcode class: %s", part)
+		}
+		classes |= 1 << n
+	}
+	return classes, nil
+}
@@ -0,0 +1,59 @@
+package emulator
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Trace(t *testing.T) {
+	em := testEmulator()
+	var out bytes.Buffer
+	em.settings.Trace = &Trace{W: &out}
+	em.Load([]uint8{
+		0x60, 0x0C, // LD V0, 0x0C
+		0xA2, 0x2A, // LD I, 0x22A
+		0x30, 0x0C, // SEQ V0, 0x0C
+		0x00, 0x00, //
+		0x81, 0x28, // unknown
+	})
+	for n := 0; n < 4; n++ {
+		inst, _ := em.fetch()
+		em.execute(inst)
+	}
+	assert.Equal(t, ""+
+		"200  600C       LD V0, 0x0C            V0=0C\n"+
+		"202  A22A       LD I, 0x22A            I=22A\n"+
+		"204  300C       SEQ V0, 0x0C           PC=208\n"+
+		"208  8128       DW 0x8128              fault: opcode not found\n", out.String())
+}
+
+func Test_Trace_filters(t *testing.T) {
+	trace := &Trace{Ranges: []AddrRange{{0x200, 0x2FF}}, Classes: 1 << 0xD, Max: 2}
+	assert.True(t, trace.traces(0x200, 0xD123))
+	assert.False(t, trace.traces(0x300, 0xD123))
+	assert.False(t, trace.traces(0x200, 0x6123))
+	trace.count = 2
+	assert.False(t, trace.traces(0x200, 0xD123))
+}
+
+func Test_ParseAddrRanges(t *testing.T) {
+	ranges, err := ParseAddrRanges("0x200-0x2FF, 0x310")
+	assert.Nil(t, err)
+	assert.Equal(t, []AddrRange{{0x200, 0x2FF}, {0x310, 0x310}}, ranges)
+
+	_, err = ParseAddrRanges("0x300-0x200")
+	assert.NotNil(t, err)
+}
+
+func Test_ParseOpcodeClasses(t *testing.T) {
+	classes, err := ParseOpcodeClasses("D,Fxxx,1")
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(1<<0xD|1<<0xF|1<<0x1), classes)
+
+	_, err = ParseOpcodeClasses("DX")
+	assert.NotNil(t, err)
+	_, err = ParseOpcodeClasses("G")
+	assert.NotNil(t, err)
+}
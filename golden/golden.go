@@ -0,0 +1,181 @@
+// Package golden helps write regression tests for roms. A rom is run
+// headlessly for a number of frames with scripted input, the screen it
+// ends on is rendered as text or a png and compared against a golden
+// file. Running the tests with -update rewrites the golden files.
+//
+//	func Test_Pong(t *testing.T) {
+//		d := golden.Run{Frames: 120}.ROM(t, "pong.ch8")
+//		golden.AssertText(t, "testdata/pong.txt", d)
+//	}
+package golden
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bchadwic/chip8/emulator"
+	"github.com/bchadwic/chip8/internal/display"
+	"github.com/bchadwic/chip8/movie"
+)
+
+// width in real pixels of a display pixel in png golden files
+const PNG_SCALE = 4
+
+var update = flag.Bool("update", false, "rewrite golden files with the current output")
+
+// PALETTE colors png golden files, indexed by the planes a pixel is on in
+var PALETTE = display.Palette("white")
+
+// Input holds keys down for a number of frames
+type Input struct {
+	Keys   []uint8
+	Frames int
+}
+
+// Run describes how a rom is run
+type Run struct {
+	Platform emulator.Platform
+	// seeds CXNN, defaults to 1 so random roms draw the same screen
+	Seed   uint64
+	Frames int
+	// played in order from the first frame, no keys
+	// are pressed once the input runs out
+	Input []Input
+	// keys FX0A receives in order as soon as it asks, once
+	// they run out FX0A waits while the frames go by
+	Waits []uint8
+}
+
+// ROM runs the rom file at path, returning its display
+func (r Run) ROM(t testing.TB, path string) display.Display {
+	t.Helper()
+	rom, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read rom: %v", err)
+	}
+	return r.Bytes(t, rom)
+}
+
+// Bytes runs rom, returning its display
+func (r Run) Bytes(t testing.TB, rom []uint8) display.Display {
+	t.Helper()
+	seed := r.Seed
+	if seed == 0 {
+		seed = 1
+	}
+	em := emulator.Create(&emulator.EmulatorSettings{
+		Headless: true,
+		Platform: r.Platform,
+		Quirks:   r.Platform.Quirks(),
+		Seed:     seed,
+		Keypad:   movie.Play(r.movie()),
+	})
+	if err := em.LoadROM(rom); err != nil {
+		t.Fatalf("could not load rom: %v", err)
+	}
+	for f := 0; f < r.Frames && !em.Halted(); f++ {
+		if err := em.RunFrame(); err != nil {
+			t.Fatalf("frame %d: %v", f, err)
+		}
+	}
+	if f := em.Fault(); f != nil {
+		t.Fatalf("rom faulted: %v", f)
+	}
+	return em.Display()
+}
+
+// movie scripts the input as a movie so it is played back like a recording
+func (r Run) movie() *movie.Movie {
+	m := &movie.Movie{}
+	// waits are due from the first frame
+	for _, k := range r.Waits {
+		m.Events = append(m.Events, movie.Event{Wait: true, Key: k})
+	}
+	for _, in := range r.Input {
+		var keys uint16
+		for _, k := range in.Keys {
+			keys |= 1 << (k & 0xF)
+		}
+		m.Events = append(m.Events, movie.Event{Keys: keys, Frames: in.Frames})
+	}
+	return m
+}
+
+// AssertText compares the display drawn as text against the golden file at path
+func AssertText(t testing.TB, path string, d display.Display) {
+	t.Helper()
+	got := display.Text(d)
+	want, ok := read(t, path, []byte(got))
+	if !ok {
+		return
+	}
+	if diff := display.Diff(string(want), got); diff != "" {
+		t.Errorf("screen differs from %s, rerun with -update if this is expected\n%s", path, diff)
+	}
+}
+
+// AssertPNG compares the display drawn as a png against the golden file at path
+func AssertPNG(t testing.TB, path string, d display.Display) {
+	t.Helper()
+	img := display.Image(d, PNG_SCALE, PALETTE)
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		t.Fatalf("could not encode png: %v", err)
+	}
+	want, ok := read(t, path, b.Bytes())
+	if !ok {
+		return
+	}
+	golden, err := png.Decode(bytes.NewReader(want))
+	if err != nil {
+		t.Fatalf("could not decode %s: %v", path, err)
+	}
+	if golden.Bounds() != img.Bounds() {
+		t.Errorf("screen is %v, %s is %v", img.Bounds().Size(), path, golden.Bounds().Size())
+		return
+	}
+	if n := differing(golden, img); n > 0 {
+		t.Errorf("%d pixels differ from %s, rerun with -update if this is expected", n, path)
+	}
+}
+
+// read returns the golden file at path, with -update got is
+// written in its place and there is nothing to compare
+func read(t testing.TB, path string, got []byte) ([]byte, bool) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("could not update golden file: %v", err)
+		}
+		return nil, false
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read golden file, run with -update to create it: %v", err)
+	}
+	return want, true
+}
+
+// differing counts the pixels of a and b that are different colors
+func differing(a, b image.Image) int {
+	n := 0
+	bounds := a.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r1, g1, b1, a1 := a.At(x, y).RGBA()
+			r2, g2, b2, a2 := b.At(x, y).RGBA()
+			if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+				n++
+			}
+		}
+	}
+	return n
+}
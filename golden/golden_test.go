@@ -0,0 +1,33 @@
+package golden
+
+import (
+	"testing"
+
+	"github.com/bchadwic/chip8/movie"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_movie(t *testing.T) {
+	r := Run{
+		Input: []Input{{Frames: 3}, {Keys: []uint8{0x1, 0xF}, Frames: 2}},
+		Waits: []uint8{0x4},
+	}
+	assert.Equal(t, []movie.Event{
+		{Wait: true, Key: 0x4},
+		{Keys: 0x0000, Frames: 3},
+		{Keys: 0x8002, Frames: 2},
+	}, r.movie().Events)
+}
+
+func Test_Bytes(t *testing.T) {
+	rom := []uint8{
+		0x60, 0x05, // LD V0, 5
+		0xE0, 0x9E, // SKP V0
+		0x12, 0x02, // JMP 0x202
+		0xF0, 0x29, // LD F, V0
+		0xD1, 0x15, // DRW V1, V1, 5
+		0x12, 0x0A, // JMP 0x20A
+	}
+	d := Run{Frames: 4, Input: []Input{{Frames: 2}, {Keys: []uint8{0x5}, Frames: 1}}}.Bytes(t, rom)
+	AssertText(t, "testdata/five.txt", d)
+}
@@ -1,29 +1,56 @@
 package display
 
 import (
+	"sync"
+
 	"github.com/bchadwic/chip8/internal/display/emit"
 )
 
+// Display is a screen made of one or more bitplanes, every
+// operation acts on the planes currently selected
 type Display interface {
 	Clear()
 	Get(row, col uint8) emit.Emit
 	Set(e emit.Emit, row, col uint8)
+	Scroll(rows, cols int)
+	SetResolution(rows, cols uint8)
+	SelectPlanes(mask uint8)
 	Pixels() []Pixel
 	WindowSize() (int, int)
+	Snapshot() State
+	Restore(s State)
 }
 
 const (
 	SCALE = 10
+
+	// xo-chip draws to two bitplanes
+	PLANES = 2
 )
 
 type display struct {
 	rows, cols int
-	screen     []emit.Emit
+	// planes are stored back to back
+	screen []emit.Emit
+	// bitmask of the selected planes
+	planes uint8
+	mu     sync.Mutex
 }
 
 type Pixel struct {
 	Row, Col int
 	Status   emit.Emit
+	// bitmask of the planes the pixel is on in,
+	// used to index a four color palette
+	Color uint8
+}
+
+// State is a copy of the display, planes are stored back to back
+type State struct {
+	Rows, Cols uint8
+	// bitmask of the selected planes
+	Planes uint8
+	Screen []emit.Emit
 }
 
 func Create(rows, cols uint8) Display {
@@ -31,38 +58,128 @@ func Create(rows, cols uint8) Display {
 	display := &display{
 		rows:   irows,
 		cols:   icols,
-		screen: make([]emit.Emit, irows*icols),
+		screen: make([]emit.Emit, PLANES*irows*icols),
+		planes: 1,
 	}
 	return display
 }
 
-func (d *display) Clear() {
-	for i := 0; i < d.rows*d.cols; i++ {
-		d.screen[i] = emit.OFF
+// selected calls fn with the offset of each selected plane
+func (d *display) selected(fn func(offset int)) {
+	size := d.rows * d.cols
+	for p := 0; p*size < len(d.screen); p++ {
+		if d.planes&(1<<p) != 0 {
+			fn(p * size)
+		}
 	}
 }
 
+func (d *display) Clear() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.selected(func(offset int) {
+		for i := 0; i < d.rows*d.cols; i++ {
+			d.screen[offset+i] = emit.OFF
+		}
+	})
+}
+
 func (d *display) Get(row, col uint8) emit.Emit {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	i := int(row)*d.cols + int(col)
-	return d.screen[i]
+	e := emit.OFF
+	d.selected(func(offset int) {
+		e = e || d.screen[offset+i]
+	})
+	return e
 }
 
 func (d *display) Set(e emit.Emit, row, col uint8) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	i := int(row)*d.cols + int(col)
-	d.screen[i] = e
+	d.selected(func(offset int) {
+		d.screen[offset+i] = e
+	})
+}
+
+// Scroll moves the screen down by rows and right by cols,
+// negative values move up and left, pixels scrolled in are off
+func (d *display) Scroll(rows, cols int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.selected(func(offset int) {
+		plane := make([]emit.Emit, d.rows*d.cols)
+		for row := 0; row < d.rows; row++ {
+			for col := 0; col < d.cols; col++ {
+				srow, scol := row-rows, col-cols
+				if srow < 0 || srow >= d.rows || scol < 0 || scol >= d.cols {
+					continue
+				}
+				plane[row*d.cols+col] = d.screen[offset+srow*d.cols+scol]
+			}
+		}
+		copy(d.screen[offset:], plane)
+	})
+}
+
+// SetResolution resizes the display, clearing every plane
+func (d *display) SetResolution(rows, cols uint8) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rows, d.cols = int(rows), int(cols)
+	d.screen = make([]emit.Emit, PLANES*d.rows*d.cols)
+}
+
+// SelectPlanes sets the planes further operations act on
+func (d *display) SelectPlanes(mask uint8) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.planes = mask
 }
 
 func (d *display) Pixels() []Pixel {
-	pixels := make([]Pixel, d.rows*d.cols)
-	for i := 0; i < d.rows*d.cols; i++ {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	size := d.rows * d.cols
+	pixels := make([]Pixel, size)
+	for i := 0; i < size; i++ {
 		row := i / d.cols
 		col := i % d.cols
-		pixel := Pixel{Row: row, Col: col, Status: d.screen[i]}
+		pixel := Pixel{Row: row, Col: col}
+		for p := 0; p*size < len(d.screen); p++ {
+			if d.screen[p*size+i] == emit.ON {
+				pixel.Status = emit.ON
+				pixel.Color |= 1 << p
+			}
+		}
 		pixels[i] = pixel
 	}
 	return pixels
 }
 
 func (d *display) WindowSize() (int, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	return d.rows, d.cols
 }
+
+// Snapshot copies the display so it can be restored later
+func (d *display) Snapshot() State {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	screen := make([]emit.Emit, len(d.screen))
+	copy(screen, d.screen)
+	return State{Rows: uint8(d.rows), Cols: uint8(d.cols), Planes: d.planes, Screen: screen}
+}
+
+// Restore replaces the display with a snapshot
+func (d *display) Restore(s State) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rows, d.cols = int(s.Rows), int(s.Cols)
+	d.planes = s.Planes
+	d.screen = make([]emit.Emit, PLANES*d.rows*d.cols)
+	copy(d.screen, s.Screen)
+}
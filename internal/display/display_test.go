@@ -17,6 +17,7 @@ func Test_Clear(t *testing.T) {
 		rows:   1,
 		cols:   1,
 		screen: []emit.Emit{emit.ON},
+		planes: 1,
 	}
 	display.Clear()
 	assert.Equal(t, emit.OFF, display.screen[0])
@@ -27,6 +28,7 @@ func Test_Get(t *testing.T) {
 		rows:   2,
 		cols:   2,
 		screen: []emit.Emit{emit.ON, emit.OFF, emit.ON, emit.OFF},
+		planes: 1,
 	}
 	assert.Equal(t, emit.ON, display.Get(1, 0))
 }
@@ -36,6 +38,7 @@ func Test_Set(t *testing.T) {
 		rows:   2,
 		cols:   2,
 		screen: []emit.Emit{emit.ON, emit.OFF, emit.ON, emit.OFF},
+		planes: 1,
 	}
 	display.Set(emit.OFF, 1, 0)
 	assert.Equal(t, emit.OFF, display.Get(1, 0))
@@ -46,6 +49,7 @@ func Test_Pixels(t *testing.T) {
 		rows:   2,
 		cols:   2,
 		screen: []emit.Emit{emit.ON, emit.OFF, emit.ON, emit.OFF},
+		planes: 1,
 	}
 	pixels := display.Pixels()
 	assert.Equal(t, len(display.screen), len(pixels))
@@ -60,3 +64,71 @@ func Test_WindowSize(t *testing.T) {
 	assert.Equal(t, display.rows, rows)
 	assert.Equal(t, display.cols, cols)
 }
+
+func Test_Scroll(t *testing.T) {
+	display := &display{
+		rows:   2,
+		cols:   3,
+		screen: []emit.Emit{emit.ON, emit.OFF, emit.OFF, emit.OFF, emit.OFF, emit.OFF},
+		planes: 1,
+	}
+	display.Scroll(1, 2)
+	assert.Equal(t, []emit.Emit{emit.OFF, emit.OFF, emit.OFF, emit.OFF, emit.OFF, emit.ON}, display.screen)
+	display.Scroll(0, -1)
+	assert.Equal(t, emit.ON, display.Get(1, 1))
+	display.Scroll(-1, 0)
+	assert.Equal(t, emit.ON, display.Get(0, 1))
+	assert.Equal(t, emit.OFF, display.Get(1, 1))
+}
+
+func Test_SetResolution(t *testing.T) {
+	display := &display{
+		rows:   1,
+		cols:   1,
+		screen: []emit.Emit{emit.ON},
+		planes: 1,
+	}
+	display.SetResolution(64, 128)
+	rows, cols := display.WindowSize()
+	assert.Equal(t, 64, rows)
+	assert.Equal(t, 128, cols)
+	assert.Equal(t, PLANES*64*128, len(display.screen))
+	assert.Equal(t, emit.OFF, display.Get(63, 127))
+}
+
+func Test_SelectPlanes(t *testing.T) {
+	display := Create(1, 2).(*display)
+	display.Set(emit.ON, 0, 0)
+	display.SelectPlanes(0b10)
+	assert.Equal(t, emit.OFF, display.Get(0, 0))
+	display.Set(emit.ON, 0, 0)
+	display.Set(emit.ON, 0, 1)
+
+	pixels := display.Pixels()
+	assert.Equal(t, uint8(0b11), pixels[0].Color)
+	assert.Equal(t, uint8(0b10), pixels[1].Color)
+	assert.Equal(t, emit.ON, pixels[1].Status)
+
+	display.Clear()
+	display.SelectPlanes(0b11)
+	assert.Equal(t, emit.ON, display.Get(0, 0))
+	assert.Equal(t, emit.OFF, display.Get(0, 1))
+}
+
+func Test_Snapshot(t *testing.T) {
+	display := Create(1, 2).(*display)
+	display.Set(emit.ON, 0, 1)
+	state := display.Snapshot()
+
+	display.SetResolution(2, 4)
+	display.SelectPlanes(0b10)
+	display.Restore(state)
+	assert.Equal(t, 1, display.rows)
+	assert.Equal(t, 2, display.cols)
+	assert.Equal(t, uint8(1), display.planes)
+	assert.Equal(t, emit.ON, display.Get(0, 1))
+
+	// the snapshot is a copy
+	display.Set(emit.OFF, 0, 1)
+	assert.Equal(t, emit.ON, state.Screen[1])
+}
@@ -0,0 +1,108 @@
+package display
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bchadwic/chip8/internal/display/emit"
+)
+
+// Palette returns the colors the window draws with for a pixel color
+// name, indexed by the planes a pixel is on in. The second plane is
+// darker and pixels on in both planes lighter.
+func Palette(name string) [4]color.Color {
+	black := color.RGBA{0x00, 0x00, 0x00, 0xFF}
+	switch strings.ToLower(name) {
+	case "red":
+		return [4]color.Color{black, color.RGBA{0xFF, 0x00, 0x00, 0xFF}, color.RGBA{0x80, 0x00, 0x00, 0xFF}, color.RGBA{0xFF, 0x80, 0x80, 0xFF}}
+	case "green":
+		return [4]color.Color{black, color.RGBA{0x00, 0xFF, 0x00, 0xFF}, color.RGBA{0x00, 0x80, 0x00, 0xFF}, color.RGBA{0x80, 0xFF, 0x80, 0xFF}}
+	case "blue":
+		return [4]color.Color{black, color.RGBA{0x00, 0x00, 0xFF, 0xFF}, color.RGBA{0x00, 0x00, 0x80, 0xFF}, color.RGBA{0x80, 0x80, 0xFF, 0xFF}}
+	case "gray", "grey":
+		return [4]color.Color{black, color.RGBA{0x80, 0x80, 0x80, 0xFF}, color.RGBA{0x40, 0x40, 0x40, 0xFF}, color.RGBA{0xC0, 0xC0, 0xC0, 0xFF}}
+	default:
+		return [4]color.Color{black, color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}, color.RGBA{0x40, 0x40, 0x40, 0xFF}, color.RGBA{0xC0, 0xC0, 0xC0, 0xFF}}
+	}
+}
+
+// Text draws the display a line per row with '.' for pixels that are
+// off and '#' for pixels on in the first plane. Pixels on in the other
+// planes use '+' for the second plane alone and '@' for both.
+func Text(d Display) string {
+	var b strings.Builder
+	_, cols := d.WindowSize()
+	for _, pixel := range d.Pixels() {
+		c := byte('.')
+		if pixel.Status == emit.ON {
+			c = " #+@"[pixel.Color]
+		}
+		b.WriteByte(c)
+		if pixel.Col == cols-1 {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// Diff describes the rows that differ between two screens drawn
+// by Text, empty when they are the same
+func Diff(want, got string) string {
+	if want == got {
+		return ""
+	}
+	wantRows := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	gotRows := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	var b strings.Builder
+	if len(wantRows) != len(gotRows) || len(wantRows[0]) != len(gotRows[0]) {
+		fmt.Fprintf(&b, "resolution is %dx%d, want %dx%d\n", len(gotRows[0]), len(gotRows), len(wantRows[0]), len(wantRows))
+		return b.String()
+	}
+	for r := range wantRows {
+		if wantRows[r] != gotRows[r] {
+			fmt.Fprintf(&b, "row %2d want %s\n       got  %s\n", r, wantRows[r], gotRows[r])
+		}
+	}
+	return b.String()
+}
+
+// Image draws the display with every pixel scale pixels wide, palette
+// is indexed by the planes a pixel is on in so the first color is off
+func Image(d Display, scale int, palette [4]color.Color) *image.Paletted {
+	rows, cols := d.WindowSize()
+	img := image.NewPaletted(image.Rect(0, 0, cols*scale, rows*scale), palette[:])
+	for _, pixel := range d.Pixels() {
+		if pixel.Status != emit.ON {
+			continue
+		}
+		for y := 0; y < scale; y++ {
+			for x := 0; x < scale; x++ {
+				img.SetColorIndex(pixel.Col*scale+x, pixel.Row*scale+y, pixel.Color)
+			}
+		}
+	}
+	return img
+}
+
+// WritePNG encodes the display as a png drawn by Image
+func WritePNG(w io.Writer, d Display, scale int, palette [4]color.Color) error {
+	return png.Encode(w, Image(d, scale, palette))
+}
+
+// Screenshot writes the display to a png file at path
+func Screenshot(d Display, path string, scale int, palette [4]color.Color) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WritePNG(f, d, scale, palette); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
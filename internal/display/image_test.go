@@ -0,0 +1,50 @@
+package display
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/bchadwic/chip8/internal/display/emit"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Text(t *testing.T) {
+	display := &display{
+		rows: 2,
+		cols: 2,
+		screen: []emit.Emit{
+			emit.ON, emit.OFF, emit.ON, emit.OFF,
+			emit.OFF, emit.OFF, emit.ON, emit.ON,
+		},
+		planes: 1,
+	}
+	assert.Equal(t, "#.\n@+\n", Text(display))
+}
+
+func Test_Diff(t *testing.T) {
+	assert.Empty(t, Diff("#.\n.#\n", "#.\n.#\n"))
+	assert.Equal(t, "row  1 want .#\n       got  ##\n", Diff("#.\n.#\n", "#.\n##\n"))
+	assert.Equal(t, "resolution is 3x1, want 2x2\n", Diff("#.\n.#\n", "#.#\n"))
+}
+
+func Test_Image(t *testing.T) {
+	display := &display{
+		rows:   1,
+		cols:   2,
+		screen: []emit.Emit{emit.OFF, emit.ON, emit.OFF, emit.ON},
+		planes: 1,
+	}
+	palette := [4]color.Color{color.Black, color.White, color.Gray{0x40}, color.Gray{0xC0}}
+	img := Image(display, 2, palette)
+	assert.Equal(t, 4, img.Bounds().Dx())
+	assert.Equal(t, 2, img.Bounds().Dy())
+	assert.Equal(t, uint8(0), img.ColorIndexAt(1, 1))
+	assert.Equal(t, uint8(3), img.ColorIndexAt(2, 0))
+	assert.Equal(t, uint8(3), img.ColorIndexAt(3, 1))
+}
+
+func Test_Palette(t *testing.T) {
+	assert.Equal(t, color.RGBA{0xFF, 0x00, 0x00, 0xFF}, Palette("ReD")[1])
+	assert.Equal(t, Palette("white"), Palette("unknown"))
+	assert.Equal(t, Palette("gray"), Palette("grey"))
+}
@@ -1,6 +1,9 @@
+// Package drivers shows the emulator in a window, it is
+// the default frontend.
 package drivers
 
 import (
+	"fmt"
 	"log"
 	"strings"
 	"sync"
@@ -8,50 +11,81 @@ import (
 
 	"github.com/bchadwic/chip8/internal/display"
 	"github.com/bchadwic/chip8/internal/display/emit"
-	"github.com/bchadwic/chip8/internal/keypad"
+	"github.com/bchadwic/chip8/internal/frontend"
+	"github.com/bchadwic/chip8/internal/hotkey"
+	"github.com/bchadwic/chip8/internal/keymap"
 	"github.com/bchadwic/chip8/internal/speaker"
 	"github.com/gonutz/prototype/draw"
 )
 
 type driverContext struct {
-	speaker speaker.Speaker
-	keypad  keypad.Keypad
-	display display.Display
-
 	// display settings
 	displayInitialized bool
 	frameRate          int
 	fill               bool
 	color              draw.Color
+	// colors indexed by the planes a pixel is on in
+	palette [4]draw.Color
+	// width of the window in real pixels
+	width int
 
 	// keyboard settings
 	keypadInitialized bool
-	keyboard          map[byte]uint8
+	keyboard          keymap.Keymap
+
+	// the window runs on its own goroutine, everything
+	// below is shared with the emulator
+	mu sync.Mutex
+	// the last display presented
+	pixels []display.Pixel
+	cols   int
+	// the last state of the speaker played
+	beeping bool
+	pattern []uint8
+	pitch   uint8
+	// audio pattern files played
+	sounds sounds
+	// set once Close is called, nothing is played after
+	closed bool
+	// input waiting to be polled
+	input frontend.Input
+	// keys the window can not say are held, such as punctuation,
+	// are held until they stop repeating
+	typed frontend.Typed
+	// keypad keys held as of the last update
+	held uint16
+	// closed once the window is closed
+	quit      chan struct{}
+	closeOnce sync.Once
 
 	frame int
 }
 
-var qwerty map[byte]uint8 = map[byte]uint8{
-	'1': 0x1, '2': 0x2, '3': 0x3, '4': 0xC,
-	'q': 0x4, 'w': 0x5, 'e': 0x6, 'r': 0xD,
-	'a': 0x7, 's': 0x8, 'd': 0x9, 'f': 0xE,
-	'z': 0xA, 'x': 0x0, 'c': 0xB, 'v': 0xF,
-}
+const (
+	SAVE_STATE_KEY = draw.KeyF5
+	LOAD_STATE_KEY = draw.KeyF9
+	REWIND_KEY     = draw.KeyBackspace
+	SCREENSHOT_KEY = draw.KeyF12
+	RECORD_KEY     = draw.KeyF10
+)
 
-var dvorak map[byte]uint8 = map[byte]uint8{
-	'1': 0x1, '2': 0x2, '3': 0x3, '4': 0xC,
-	'\'': 0x4, ',': 0x5, '.': 0x6, 'p': 0xD,
-	'a': 0x7, 'o': 0x8, 'e': 0x9, 'u': 0xE,
-	';': 0xA, 'q': 0x0, 'j': 0xB, 'k': 0xF,
+// named keys that can be read while they are held
+var namedKeys = map[string]draw.Key{
+	keymap.SPACE: draw.KeySpace,
+	keymap.ENTER: draw.KeyEnter,
+	keymap.TAB:   draw.KeyTab,
+	keymap.UP:    draw.KeyUp,
+	keymap.DOWN:  draw.KeyDown,
+	keymap.LEFT:  draw.KeyLeft,
+	keymap.RIGHT: draw.KeyRight,
 }
 
-func Create(speaker speaker.Speaker, keypad keypad.Keypad, display display.Display) *driverContext {
+func Create() *driverContext {
 	return &driverContext{
-		speaker: speaker,
-		keypad:  keypad,
-		display: display,
 		fill:    true,
 		color:   draw.White,
+		palette: [4]draw.Color{draw.Black, draw.White, draw.DarkGray, draw.LightGray},
+		quit:    make(chan struct{}),
 	}
 }
 
@@ -59,87 +93,185 @@ func (dc *driverContext) DisplaySettings(frameRate int, fill bool, color string)
 	dc.displayInitialized = true
 	dc.frameRate = frameRate
 	dc.fill = fill
+	// the second plane is drawn darker and
+	// pixels on in both planes lighter
 	switch strings.ToLower(color) {
 	case "red":
 		dc.color = draw.Red
+		dc.palette = [4]draw.Color{draw.Black, draw.Red, draw.DarkRed, draw.LightRed}
 	case "green":
 		dc.color = draw.Green
+		dc.palette = [4]draw.Color{draw.Black, draw.Green, draw.DarkGreen, draw.LightGreen}
 	case "blue":
 		dc.color = draw.Blue
+		dc.palette = [4]draw.Color{draw.Black, draw.Blue, draw.DarkBlue, draw.LightBlue}
 	case "gray", "grey":
 		dc.color = draw.Gray
+		dc.palette = [4]draw.Color{draw.Black, draw.Gray, draw.DarkGray, draw.LightGray}
 	default:
 		dc.color = draw.White
+		dc.palette = [4]draw.Color{draw.Black, draw.White, draw.DarkGray, draw.LightGray}
 	}
 	return dc
 }
 
-func (dc *driverContext) KeypadSettings(keyboard string) *driverContext {
+func (dc *driverContext) KeypadSettings(keyboard keymap.Keymap) *driverContext {
 	dc.keypadInitialized = true
-	switch strings.ToLower(keyboard) {
-	case "dvorak":
-		dc.keyboard = dvorak
-	default:
-		dc.keyboard = qwerty
-	}
+	dc.keyboard = keyboard
 	return dc
 }
 
-func (dc *driverContext) Start() {
+// Open opens the window, which keeps its size when the resolution changes
+func (dc *driverContext) Open(rows, cols int) error {
 	if !dc.displayInitialized {
-		log.Fatal("display driver was not initialized")
+		return fmt.Errorf("display driver was not initialized")
 	}
 	if !dc.keypadInitialized {
-		log.Fatal("keypad driver was not initialized")
+		return fmt.Errorf("keypad driver was not initialized")
 	}
-	rows, cols := dc.display.WindowSize()
-	err := draw.RunWindow("CHIP-8", cols*display.SCALE, rows*display.SCALE, dc.update)
-	if err != nil {
-		log.Fatalf("an error occurred starting driver: %v", err)
+	dc.width = cols * display.SCALE
+	go func() {
+		err := draw.RunWindow("CHIP-8", cols*display.SCALE, rows*display.SCALE, dc.update)
+		if err != nil {
+			log.Fatalf("an error occurred starting driver: %v", err)
+		}
+		dc.closeOnce.Do(func() { close(dc.quit) })
+	}()
+	return nil
+}
+
+func (dc *driverContext) Present(d display.Display) {
+	_, cols := d.WindowSize()
+	pixels := d.Pixels()
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.pixels, dc.cols = pixels, cols
+}
+
+func (dc *driverContext) Play(s speaker.Speaker) {
+	pattern, pitch := s.Pattern()
+	active := s.IsActive()
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.beeping, dc.pattern, dc.pitch = active, pattern, pitch
+}
+
+func (dc *driverContext) Poll() frontend.Input {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	in := dc.input
+	dc.input = frontend.Input{}
+	return in
+}
+
+func (dc *driverContext) Quit() <-chan struct{} {
+	return dc.quit
+}
+
+// Close removes the audio pattern files played, the
+// window is left to close with the process
+func (dc *driverContext) Close() {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.closed = true
+	if err := dc.sounds.remove(); err != nil {
+		log.Printf("could not remove audio patterns: %v", err)
 	}
 }
 
-func (dc *driverContext) update(devices draw.Window) {
+func (dc *driverContext) update(window draw.Window) {
 	// rate limit the updates
 	time.Sleep(1 * time.Millisecond)
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
 	dc.frame++
-	var wg sync.WaitGroup
-	wg.Add(3)
-
-	go dc.renderDisplay(&wg, devices)
-	go dc.readKeyboard(&wg, devices)
-	go dc.playSpeakers(&wg, devices)
-
-	wg.Wait()
+	dc.renderDisplay(window)
+	dc.readKeyboard(window)
+	dc.playSpeakers(window)
 }
 
-func (dc *driverContext) renderDisplay(wg *sync.WaitGroup, window draw.Window) {
-	defer wg.Done()
-	for _, pixel := range dc.display.Pixels() {
+func (dc *driverContext) renderDisplay(window draw.Window) {
+	if dc.cols == 0 {
+		return
+	}
+	// the window keeps its size when the resolution changes,
+	// so pixels shrink as the resolution grows
+	scale := dc.width / dc.cols
+	for _, pixel := range dc.pixels {
 		c := draw.Black
 		if pixel.Status == emit.ON {
-			c = dc.color
+			c = dc.palette[pixel.Color]
 		}
 		if dc.fill {
-			window.FillRect(pixel.Col*display.SCALE, pixel.Row*display.SCALE, 10, 10, c)
+			window.FillRect(pixel.Col*scale, pixel.Row*scale, scale, scale, c)
 		} else {
-			window.DrawRect(pixel.Col*display.SCALE, pixel.Row*display.SCALE, 10, 10, c)
+			window.DrawRect(pixel.Col*scale, pixel.Row*scale, scale, scale, c)
 		}
 	}
 }
 
-func (dc *driverContext) readKeyboard(wg *sync.WaitGroup, keyboard draw.Window) {
-	defer wg.Done()
-	chs := keyboard.Characters()
-	for _, c := range chs {
-		key := dc.keyboard[uint8(c)]
-		dc.keypad.Set(key)
+func (dc *driverContext) readKeyboard(keyboard draw.Window) {
+	if keyboard.WasKeyPressed(SAVE_STATE_KEY) {
+		dc.input.Hotkeys = append(dc.input.Hotkeys, hotkey.SAVE_STATE)
+	}
+	if keyboard.WasKeyPressed(LOAD_STATE_KEY) {
+		dc.input.Hotkeys = append(dc.input.Hotkeys, hotkey.LOAD_STATE)
+	}
+	if keyboard.WasKeyPressed(SCREENSHOT_KEY) {
+		dc.input.Hotkeys = append(dc.input.Hotkeys, hotkey.SCREENSHOT)
+	}
+	if keyboard.WasKeyPressed(RECORD_KEY) {
+		dc.input.Hotkeys = append(dc.input.Hotkeys, hotkey.RECORD)
 	}
+	if keyboard.IsKeyDown(REWIND_KEY) {
+		dc.input.Hotkeys = append(dc.input.Hotkeys, hotkey.REWIND)
+	}
+	now := time.Now()
+	for _, c := range keyboard.Characters() {
+		key, ok := dc.keyboard.Key(c)
+		if _, held := windowKey(strings.ToLower(string(c))); ok && !held {
+			dc.typed.Type(key, now)
+		}
+	}
+	held := dc.typed.Held(now)
+	for name, key := range dc.keyboard {
+		if k, ok := windowKey(name); ok && keyboard.IsKeyDown(k) {
+			held |= 1 << key
+		}
+	}
+	dc.input.Keys = append(dc.input.Keys, frontend.Changes(dc.held, held)...)
+	dc.held = held
+}
+
+// windowKey returns the key of the window for a host key, which
+// is only known for letters, digits and the named keys
+func windowKey(name string) (draw.Key, bool) {
+	if len(name) == 1 {
+		switch c := name[0]; {
+		case c >= 'a' && c <= 'z':
+			return draw.KeyA + draw.Key(c-'a'), true
+		case c >= '0' && c <= '9':
+			return draw.Key0 + draw.Key(c-'0'), true
+		}
+	}
+	if name == " " {
+		name = keymap.SPACE
+	}
+	k, ok := namedKeys[name]
+	return k, ok
 }
 
-func (dc *driverContext) playSpeakers(wg *sync.WaitGroup, speakers draw.Window) {
-	defer wg.Done()
-	if dc.frame%dc.frameRate == 0 && dc.speaker.IsActive() {
-		speakers.PlaySoundFile("beep.wav")
+func (dc *driverContext) playSpeakers(speakers draw.Window) {
+	if dc.frame%dc.frameRate == 0 && dc.beeping && !dc.closed {
+		if dc.pattern == nil {
+			speakers.PlaySoundFile("beep.wav")
+			return
+		}
+		fname, err := dc.sounds.file(dc.pattern, dc.pitch, float64(dc.frameRate)/60)
+		if err != nil {
+			log.Printf("could not play audio pattern: %v", err)
+			return
+		}
+		speakers.PlaySoundFile(fname)
 	}
 }
@@ -3,27 +3,72 @@ package drivers
 import (
 	"testing"
 
+	"github.com/bchadwic/chip8/internal/display"
+	"github.com/bchadwic/chip8/internal/frontend"
+	"github.com/bchadwic/chip8/internal/keymap"
 	"github.com/gonutz/prototype/draw"
 	"github.com/stretchr/testify/assert"
 )
 
 func Test_Create(t *testing.T) {
-	dc := Create(nil, nil, nil)
+	dc := Create()
 	assert.NotNil(t, dc)
 }
 
 func Test_DisplaySettings(t *testing.T) {
-	dc := Create(nil, nil, nil)
+	dc := Create()
 	dc.DisplaySettings(1, true, "GrAy")
 	assert.True(t, dc.displayInitialized)
 	assert.True(t, dc.fill)
 	assert.Equal(t, 1, dc.frameRate)
 	assert.Equal(t, draw.Gray, dc.color)
+	assert.Equal(t, draw.Gray, dc.palette[1])
 }
 
 func Test_KeypadSettings(t *testing.T) {
-	dc := Create(nil, nil, nil)
-	dc.KeypadSettings("Qwerty")
+	dc := Create()
+	dc.KeypadSettings(keymap.QWERTY)
 	assert.True(t, dc.keypadInitialized)
-	assert.Equal(t, qwerty, dc.keyboard)
+	assert.Equal(t, keymap.QWERTY, dc.keyboard)
+}
+
+func Test_Open(t *testing.T) {
+	dc := Create()
+	assert.Error(t, dc.Open(32, 64))
+	dc.KeypadSettings(keymap.QWERTY)
+	assert.Error(t, dc.Open(32, 64))
+}
+
+func Test_Poll(t *testing.T) {
+	dc := Create()
+	dc.input.Keys = []frontend.KeyEvent{{Key: 0x1, Down: true}}
+	assert.Equal(t, frontend.Input{Keys: []frontend.KeyEvent{{Key: 0x1, Down: true}}}, dc.Poll())
+	assert.Equal(t, frontend.Input{}, dc.Poll())
+}
+
+func Test_windowKey(t *testing.T) {
+	k, ok := windowKey("q")
+	assert.True(t, ok)
+	assert.Equal(t, draw.KeyQ, k)
+	k, ok = windowKey("4")
+	assert.True(t, ok)
+	assert.Equal(t, draw.Key4, k)
+	k, ok = windowKey(" ")
+	assert.True(t, ok)
+	assert.Equal(t, draw.KeySpace, k)
+	k, ok = windowKey(keymap.UP)
+	assert.True(t, ok)
+	assert.Equal(t, draw.KeyUp, k)
+	// punctuation is only seen as it is typed
+	_, ok = windowKey(";")
+	assert.False(t, ok)
+}
+
+func Test_Present(t *testing.T) {
+	dc := Create()
+	d := display.Create(32, 64)
+	d.SetResolution(64, 128)
+	dc.Present(d)
+	assert.Equal(t, 128, dc.cols)
+	assert.Len(t, dc.pixels, 64*128)
 }
@@ -0,0 +1,60 @@
+package drivers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bchadwic/chip8/internal/speaker"
+)
+
+// most audio pattern files kept at once, roms that keep changing
+// their pattern or pitch have the oldest files removed
+const MAX_SOUNDS = 32
+
+// sounds renders audio patterns to wav files that can be played, the
+// window can only play files. Files are kept in a directory of their
+// own, which is removed along with them once the window closes
+type sounds struct {
+	dir string
+	// files written by name, oldest first
+	files []string
+}
+
+// file returns a wav file playing pattern at pitch for seconds, every
+// pattern gets its own file so it is only rendered once
+func (s *sounds) file(pattern []uint8, pitch uint8, seconds float64) (string, error) {
+	if s.dir == "" {
+		dir, err := os.MkdirTemp("", "chip8-sounds-")
+		if err != nil {
+			return "", err
+		}
+		s.dir = dir
+	}
+	name := fmt.Sprintf("%x-%02x-%d.wav", pattern, pitch, int(seconds*1000))
+	fname := filepath.Join(s.dir, name)
+	for _, f := range s.files {
+		if f == name {
+			return fname, nil
+		}
+	}
+	if err := os.WriteFile(fname, speaker.Wave(pattern, pitch, seconds), 0o644); err != nil {
+		return "", err
+	}
+	s.files = append(s.files, name)
+	if len(s.files) > MAX_SOUNDS {
+		os.Remove(filepath.Join(s.dir, s.files[0]))
+		s.files = s.files[1:]
+	}
+	return fname, nil
+}
+
+// remove deletes every file written
+func (s *sounds) remove() error {
+	if s.dir == "" {
+		return nil
+	}
+	err := os.RemoveAll(s.dir)
+	s.dir, s.files = "", nil
+	return err
+}
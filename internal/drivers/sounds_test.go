@@ -0,0 +1,35 @@
+package drivers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_sounds(t *testing.T) {
+	var s sounds
+	pattern := make([]uint8, 16)
+	fname, err := s.file(pattern, 64, 0.1)
+	assert.NoError(t, err)
+	again, err := s.file(pattern, 64, 0.1)
+	assert.NoError(t, err)
+	assert.Equal(t, fname, again)
+	dir := filepath.Dir(fname)
+
+	// the oldest files are removed once there are too many
+	for pitch := 0; pitch < MAX_SOUNDS; pitch++ {
+		_, err := s.file(pattern, uint8(pitch), 0.1)
+		assert.NoError(t, err)
+	}
+	_, err = os.Stat(fname)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, MAX_SOUNDS)
+
+	assert.NoError(t, s.remove())
+	_, err = os.Stat(dir)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
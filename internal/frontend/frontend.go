@@ -0,0 +1,129 @@
+// Package frontend is how the emulator shows itself to a player. The
+// emulator drives a frontend a frame at a time, presenting the display,
+// sounding the speaker and polling for input, so a window, a terminal
+// or a test can all stand in for one another.
+package frontend
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bchadwic/chip8/internal/display"
+	"github.com/bchadwic/chip8/internal/hotkey"
+	"github.com/bchadwic/chip8/internal/speaker"
+)
+
+// Frontend is driven by the emulator from a single goroutine, it should
+// never block for long as the emulator waits on it every frame
+type Frontend interface {
+	// Open starts showing the machine, rows and cols are the size of the display
+	Open(rows, cols int) error
+	// Present shows the display as it is at the end of a frame
+	Present(d display.Display)
+	// Play sounds the speaker while it is active, called every frame
+	Play(s speaker.Speaker)
+	// Poll returns the input since the last poll, it is called once a frame
+	Poll() Input
+	// Quit is closed once the player asks to quit, such as by closing the window
+	Quit() <-chan struct{}
+	// Close puts back anything Open changed, it is safe to call more than once
+	Close()
+}
+
+// Input is what the player did between polls
+type Input struct {
+	// keypad keys pressed and released, in the order they happened
+	Keys    []KeyEvent
+	Hotkeys []hotkey.Hotkey
+}
+
+// KeyEvent is a keypad key going down or coming back up
+type KeyEvent struct {
+	Key  uint8
+	Down bool
+}
+
+// Changes returns the events that take the keypad keys held in
+// prev to the ones held in held, key n is bit n
+func Changes(prev, held uint16) []KeyEvent {
+	var events []KeyEvent
+	for key := uint8(0); key < 16; key++ {
+		bit := uint16(1) << key
+		if prev&bit != held&bit {
+			events = append(events, KeyEvent{Key: key, Down: held&bit != 0})
+		}
+	}
+	return events
+}
+
+const (
+	// REPEAT_DELAY is the longest keyboards usually wait before a held
+	// key starts repeating, typed keys are held this long so the first
+	// repeat arrives before they are let go
+	REPEAT_DELAY = 700 * time.Millisecond
+	// REPEAT_INTERVAL is the longest gap between repeats of a held key
+	// once it is repeating, usually keyboards repeat every 30 to 50ms
+	REPEAT_INTERVAL = 100 * time.Millisecond
+)
+
+// Typed holds down keys that are only seen as they are typed, such as
+// in a terminal, which never says when a key comes back up. A key is
+// held until its first repeat could have arrived, then for as long as
+// it keeps repeating
+type Typed struct {
+	mu   sync.Mutex
+	keys [16]typedKey
+}
+
+type typedKey struct {
+	// when the key was last typed
+	last time.Time
+	// whether the key has repeated since it was pressed
+	repeating bool
+}
+
+// Type holds down a keypad key as it was typed at now
+func (t *Typed) Type(key uint8, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if key >= 16 {
+		return
+	}
+	k := &t.keys[key]
+	k.repeating = k.held(now)
+	k.last = now
+}
+
+// Held returns the keypad keys still held down at now, key n is bit n
+func (t *Typed) Held(now time.Time) uint16 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var held uint16
+	for key, k := range t.keys {
+		if k.held(now) {
+			held |= 1 << key
+		}
+	}
+	return held
+}
+
+func (k typedKey) held(now time.Time) bool {
+	if k.last.IsZero() {
+		return false
+	}
+	if k.repeating {
+		return now.Sub(k.last) < REPEAT_INTERVAL
+	}
+	return now.Sub(k.last) < REPEAT_DELAY
+}
+
+// Headless is a frontend that shows nothing and is never
+// given input, it can only quit by the process exiting
+type Headless struct{}
+
+func (Headless) Open(rows, cols int) error { return nil }
+func (Headless) Present(d display.Display) {}
+func (Headless) Play(s speaker.Speaker)    {}
+func (Headless) Poll() Input               { return Input{} }
+func (Headless) Quit() <-chan struct{}     { return nil }
+func (Headless) Close()                    {}
@@ -0,0 +1,49 @@
+package frontend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Changes(t *testing.T) {
+	assert.Empty(t, Changes(0x0012, 0x0012))
+	assert.Equal(t, []KeyEvent{
+		{Key: 0x1, Down: false},
+		{Key: 0x2, Down: true},
+		{Key: 0xF, Down: true},
+	}, Changes(0x0012, 0x8014))
+}
+
+func Test_Typed(t *testing.T) {
+	var typed Typed
+	now := time.Now()
+	assert.Zero(t, typed.Held(now))
+
+	typed.Type(0x5, now)
+	typed.Type(0x10, now)
+	assert.Equal(t, uint16(0x0020), typed.Held(now))
+	// held until the first repeat can arrive
+	assert.Equal(t, uint16(0x0020), typed.Held(now.Add(REPEAT_DELAY-time.Millisecond)))
+	assert.Zero(t, typed.Held(now.Add(REPEAT_DELAY)))
+}
+
+func Test_Typed_repeat(t *testing.T) {
+	var typed Typed
+	now := time.Now()
+	typed.Type(0x5, now)
+	// the first repeat arrives after the repeat delay, then quickly
+	now = now.Add(REPEAT_DELAY / 2)
+	typed.Type(0x5, now)
+	now = now.Add(REPEAT_INTERVAL / 2)
+	typed.Type(0x5, now)
+	assert.Equal(t, uint16(0x0020), typed.Held(now.Add(REPEAT_INTERVAL-time.Millisecond)))
+	// let go once the repeats stop
+	assert.Zero(t, typed.Held(now.Add(REPEAT_INTERVAL)))
+
+	// pressed again, it is held until it could repeat
+	now = now.Add(time.Second)
+	typed.Type(0x5, now)
+	assert.Equal(t, uint16(0x0020), typed.Held(now.Add(REPEAT_INTERVAL)))
+}
@@ -0,0 +1,27 @@
+// Package hotkey carries actions requested from the window
+// back to the emulator, which handles them between frames.
+package hotkey
+
+type Hotkey int
+
+const (
+	SAVE_STATE Hotkey = iota
+	LOAD_STATE
+	// sent every window update while the rewind key is held
+	REWIND
+	SCREENSHOT
+	// starts a recording, or stops the one in progress
+	RECORD
+)
+
+// Send queues a hotkey without blocking, dropping it if
+// the emulator has not caught up with earlier ones
+func Send(hotkeys chan<- Hotkey, h Hotkey) {
+	if hotkeys == nil {
+		return
+	}
+	select {
+	case hotkeys <- h:
+	default:
+	}
+}
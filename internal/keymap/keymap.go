@@ -0,0 +1,297 @@
+// Package keymap binds keys of the host keyboard to the 16 keys of the
+// keypad. Keymaps are built in or read from json files that bind host
+// keys to keypad keys written in hex, with overrides for single roms:
+//
+//	{
+//		"keys": {
+//			"1": "1", "2": "2", "3": "3", "4": "C",
+//			"q": "4", "w": "5", "e": "6", "r": "D",
+//			"a": "7", "s": "8", "d": "9", "f": "E",
+//			"z": "A", "x": "0", "c": "B", "v": "F"
+//		},
+//		"roms": {
+//			"pong.ch8": { "up": "1", "down": "4" }
+//		}
+//	}
+//
+// Host keys are a single character or one of the NAMED keys.
+package keymap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// keys of the keypad
+const KEYS = 16
+
+const (
+	SPACE = "space"
+	ENTER = "enter"
+	TAB   = "tab"
+	UP    = "up"
+	DOWN  = "down"
+	LEFT  = "left"
+	RIGHT = "right"
+)
+
+// NAMED are the host keys that are not a character
+var NAMED = []string{SPACE, ENTER, TAB, UP, DOWN, LEFT, RIGHT}
+
+// Keymap binds host keys to keypad keys
+type Keymap map[string]uint8
+
+// QWERTY binds the left of a qwerty keyboard to the keypad
+var QWERTY = Keymap{
+	"1": 0x1, "2": 0x2, "3": 0x3, "4": 0xC,
+	"q": 0x4, "w": 0x5, "e": 0x6, "r": 0xD,
+	"a": 0x7, "s": 0x8, "d": 0x9, "f": 0xE,
+	"z": 0xA, "x": 0x0, "c": 0xB, "v": 0xF,
+}
+
+// DVORAK binds the same keys as QWERTY on a dvorak keyboard
+var DVORAK = Keymap{
+	"1": 0x1, "2": 0x2, "3": 0x3, "4": 0xC,
+	"'": 0x4, ",": 0x5, ".": 0x6, "p": 0xD,
+	"a": 0x7, "o": 0x8, "e": 0x9, "u": 0xE,
+	";": 0xA, "q": 0x0, "j": 0xB, "k": 0xF,
+}
+
+// Layout returns the built in keymap of the named
+// keyboard, defaulting to qwerty
+func Layout(keyboard string) Keymap {
+	switch strings.ToLower(keyboard) {
+	case "dvorak":
+		return DVORAK
+	default:
+		return QWERTY
+	}
+}
+
+// Key returns the keypad key bound to a typed character
+func (km Keymap) Key(c rune) (uint8, bool) {
+	if c == ' ' {
+		key, ok := km[SPACE]
+		return key, ok
+	}
+	key, ok := km[strings.ToLower(string(c))]
+	return key, ok
+}
+
+// Missing returns the keypad keys nothing is bound to
+func (km Keymap) Missing() []uint8 {
+	var bound [KEYS]bool
+	for _, key := range km {
+		bound[key] = true
+	}
+	var missing []uint8
+	for key := uint8(0); key < KEYS; key++ {
+		if !bound[key] {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// Error is a problem with a single binding of a keymap file
+type Error struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// ErrorList is every problem found in a keymap file
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, e := range l {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// File is a keymap file
+type File struct {
+	Name string
+	Keys Keymap
+	// overrides by rom file name
+	ROMs map[string]Keymap
+}
+
+// Load reads and validates the keymap file at path
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, data)
+}
+
+// Parse validates a keymap file, every problem is reported in an
+// ErrorList: host keys bound twice, unknown host keys, keypad keys
+// that are not hex, and keypad keys nothing is bound to
+func Parse(name string, data []byte) (*File, error) {
+	p := &parser{name: name, data: data, dec: json.NewDecoder(bytes.NewReader(data))}
+	f := &File{Name: name, ROMs: make(map[string]Keymap)}
+	err := p.object(func(field string) error {
+		switch field {
+		case "keys":
+			if f.Keys != nil {
+				p.errorf("keys is given twice")
+			}
+			p.keysLine = p.line()
+			km, err := p.bindings()
+			f.Keys = km
+			return err
+		case "roms":
+			return p.object(func(rom string) error {
+				if _, ok := f.ROMs[rom]; ok {
+					p.errorf("rom %s is given twice", rom)
+				}
+				km, err := p.bindings()
+				f.ROMs[rom] = km
+				return err
+			})
+		default:
+			p.errorf("unknown field %s", field)
+			var skip json.RawMessage
+			return p.dec.Decode(&skip)
+		}
+	})
+	if err != nil {
+		return nil, &Error{File: name, Line: p.line(), Msg: err.Error()}
+	}
+	if f.Keys == nil {
+		p.errs = append(p.errs, &Error{File: name, Line: 1, Msg: "no keys are bound"})
+	} else if missing := f.Keys.Missing(); len(missing) > 0 {
+		p.errs = append(p.errs, &Error{File: name, Line: p.keysLine, Msg: "nothing is bound to " + hexKeys(missing)})
+	}
+	if len(p.errs) > 0 {
+		return nil, p.errs
+	}
+	return f, nil
+}
+
+// For returns the keys bound for a rom, its overrides are
+// added to the keys and replace bindings of the same host key
+func (f *File) For(rom string) Keymap {
+	km := make(Keymap, len(f.Keys))
+	for host, key := range f.Keys {
+		km[host] = key
+	}
+	for host, key := range f.ROMs[rom] {
+		km[host] = key
+	}
+	return km
+}
+
+type parser struct {
+	name string
+	data []byte
+	dec  *json.Decoder
+	errs ErrorList
+	// line the keys object starts on
+	keysLine int
+}
+
+func (p *parser) line() int {
+	return bytes.Count(p.data[:p.dec.InputOffset()], []byte("\n")) + 1
+}
+
+func (p *parser) errorf(format string, args ...any) {
+	p.errs = append(p.errs, &Error{File: p.name, Line: p.line(), Msg: fmt.Sprintf(format, args...)})
+}
+
+// object reads a json object, calling field for each of its fields
+// after reading the name, which must then read the value
+func (p *parser) object(field func(name string) error) error {
+	if err := p.delim('{'); err != nil {
+		return err
+	}
+	for p.dec.More() {
+		tok, err := p.dec.Token()
+		if err != nil {
+			return err
+		}
+		if err := field(tok.(string)); err != nil {
+			return err
+		}
+	}
+	return p.delim('}')
+}
+
+func (p *parser) delim(want json.Delim) error {
+	tok, err := p.dec.Token()
+	if err == io.EOF {
+		return fmt.Errorf("expected %v", want)
+	}
+	if err != nil {
+		return err
+	}
+	if tok != want {
+		return fmt.Errorf("expected %v but found %v", want, tok)
+	}
+	return nil
+}
+
+// bindings reads an object of host keys to keypad keys
+func (p *parser) bindings() (Keymap, error) {
+	km := make(Keymap)
+	err := p.object(func(host string) error {
+		name := strings.ToLower(host)
+		if name == " " {
+			name = SPACE
+		}
+		var value string
+		if err := p.dec.Decode(&value); err != nil {
+			return err
+		}
+		if !valid(name) {
+			p.errorf("unknown key %q, expected a character or one of %s", host, strings.Join(NAMED, ", "))
+			return nil
+		}
+		key, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(value), "0x"), 16, 8)
+		if err != nil || key >= KEYS {
+			p.errorf("%s is bound to %q, expected a keypad key from 0 to F", host, value)
+			return nil
+		}
+		if prev, ok := km[name]; ok {
+			p.errorf("%s is bound twice, to %X and %X", host, prev, key)
+			return nil
+		}
+		km[name] = uint8(key)
+		return nil
+	})
+	return km, err
+}
+
+// valid reports whether name is a host key
+func valid(name string) bool {
+	if len([]rune(name)) == 1 {
+		return true
+	}
+	for _, named := range NAMED {
+		if name == named {
+			return true
+		}
+	}
+	return false
+}
+
+func hexKeys(keys []uint8) string {
+	s := make([]string, len(keys))
+	for i, key := range keys {
+		s[i] = fmt.Sprintf("%X", key)
+	}
+	return strings.Join(s, ", ")
+}
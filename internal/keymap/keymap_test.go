@@ -0,0 +1,95 @@
+package keymap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const qwerty = `{
+	"keys": {
+		"1": "1", "2": "2", "3": "3", "4": "C",
+		"q": "4", "w": "5", "e": "6", "r": "D",
+		"a": "7", "s": "8", "d": "9", "f": "E",
+		"z": "A", "x": "0", "c": "B", "v": "F"
+	},
+	"roms": {
+		"pong.ch8": { "Up": "1", "down": "0x4", " ": "c" }
+	}
+}`
+
+func Test_Parse(t *testing.T) {
+	f, err := Parse("qwerty.json", []byte(qwerty))
+	assert.NoError(t, err)
+	assert.Equal(t, QWERTY, f.Keys)
+	assert.Equal(t, Keymap{UP: 0x1, DOWN: 0x4, SPACE: 0xC}, f.ROMs["pong.ch8"])
+}
+
+func Test_Parse_errors(t *testing.T) {
+	src := `{
+	"keys": {
+		"1": "1", "2": "2", "3": "3", "4": "C",
+		"q": "4", "w": "5", "e": "6", "r": "D",
+		"a": "7", "s": "8", "d": "9", "f": "E",
+		"z": "A", "Q": "0", "c": "G", "pgup": "F"
+	},
+	"colors": {}
+}`
+	_, err := Parse("bad.json", []byte(src))
+	assert.EqualError(t, err, `bad.json:6: Q is bound twice, to 4 and 0
+bad.json:6: c is bound to "G", expected a keypad key from 0 to F
+bad.json:6: unknown key "pgup", expected a character or one of space, enter, tab, up, down, left, right
+bad.json:8: unknown field colors
+bad.json:2: nothing is bound to 0, B, F`)
+
+	_, err = Parse("empty.json", []byte(`{}`))
+	assert.EqualError(t, err, "empty.json:1: no keys are bound")
+
+	_, err = Parse("broken.json", []byte(`{"keys": [`))
+	assert.EqualError(t, err, "broken.json:1: expected { but found [")
+}
+
+func Test_For(t *testing.T) {
+	f, err := Parse("qwerty.json", []byte(qwerty))
+	assert.NoError(t, err)
+	km := f.For("pong.ch8")
+	assert.Equal(t, uint8(0x1), km[UP])
+	assert.Equal(t, uint8(0x1), km["1"])
+	assert.Equal(t, QWERTY, f.For("ibm.ch8"))
+	// the overrides are not added to the file's keys
+	assert.NotContains(t, f.Keys, UP)
+}
+
+func Test_Load(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "qwerty.json")
+	assert.NoError(t, os.WriteFile(path, []byte(qwerty), 0o644))
+	f, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, path, f.Name)
+}
+
+func Test_Key(t *testing.T) {
+	km := Keymap{"q": 0x4, SPACE: 0x6}
+	key, ok := km.Key('Q')
+	assert.True(t, ok)
+	assert.Equal(t, uint8(0x4), key)
+	key, ok = km.Key(' ')
+	assert.True(t, ok)
+	assert.Equal(t, uint8(0x6), key)
+	_, ok = km.Key('w')
+	assert.False(t, ok)
+}
+
+func Test_Missing(t *testing.T) {
+	assert.Empty(t, QWERTY.Missing())
+	assert.Empty(t, DVORAK.Missing())
+	assert.Len(t, Keymap{"q": 0x4}.Missing(), 15)
+}
+
+func Test_Layout(t *testing.T) {
+	assert.Equal(t, DVORAK, Layout("Dvorak"))
+	assert.Equal(t, QWERTY, Layout("qwerty"))
+	assert.Equal(t, QWERTY, Layout("colemak"))
+}
@@ -4,53 +4,134 @@ import (
 	"sync"
 )
 
+// keys on the keypad
+const KEYS = 16
+
 type Keypad interface {
 	Clear()
 	Get(kaddr uint8) bool
-	Set(kaddr uint8)
-	Next() uint8
+	Press(kaddr uint8)
+	Release(kaddr uint8)
+	// Await starts waiting for a key to be pressed and then released,
+	// replacing any earlier wait. It returns straight away, nothing is
+	// notified when the wait ends
+	Await()
+	// Awaited returns the key that ended the wait, once one has, the
+	// emulator checks it once a frame after input has been polled
+	Awaited() (uint8, bool)
+	State() uint16
+	Restore(state uint16)
+}
+
+// Framer is implemented by keypads that take their input a frame at
+// a time, Frame is called by the emulator at the start of every frame
+type Framer interface {
+	Frame()
 }
 
+// keypad tracks the keys held down on the frontend, the machine sees
+// them a frame at a time so every instruction in a frame agrees
 type keypad struct {
-	pressed map[byte]bool
-	mu      sync.Mutex
+	mu sync.Mutex
+	// keys held down right now, key n is bit n
+	down uint16
+	// keys pressed since the last frame, so a tap
+	// shorter than a frame is still seen for one
+	tapped uint16
+	// keys the machine sees this frame
+	frame uint16
+
+	// keys pressed since FX0A started waiting, the
+	// wait ends once one of them is released
+	waiting bool
+	armed   uint16
+	// the key that ended the wait, -1 until one does
+	awaited int
 }
 
 func Create() Keypad {
-	return &keypad{
-		pressed: make(map[byte]bool),
-	}
+	return &keypad{awaited: -1}
 }
 
+// Clear releases every key
 func (kp *keypad) Clear() {
 	kp.mu.Lock()
 	defer kp.mu.Unlock()
-	for kaddr := range kp.pressed {
-		kp.pressed[kaddr] = false
-	}
+	kp.down, kp.tapped, kp.frame = 0, 0, 0
+	kp.waiting, kp.armed, kp.awaited = false, 0, -1
 }
 
+// Get reports whether a key is down this frame
 func (kp *keypad) Get(kaddr uint8) bool {
 	kp.mu.Lock()
 	defer kp.mu.Unlock()
-	return kp.pressed[kaddr]
+	return kaddr < KEYS && kp.frame&(1<<kaddr) != 0
+}
+
+// Press holds a key down until it is released
+func (kp *keypad) Press(kaddr uint8) {
+	if kaddr >= KEYS {
+		return
+	}
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	kp.down |= 1 << kaddr
+	kp.tapped |= 1 << kaddr
+	if kp.waiting {
+		kp.armed |= 1 << kaddr
+	}
+}
+
+// Release lets a key back up
+func (kp *keypad) Release(kaddr uint8) {
+	if kaddr >= KEYS {
+		return
+	}
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	kp.down &^= 1 << kaddr
+	if kp.waiting && kp.armed&(1<<kaddr) != 0 {
+		kp.waiting, kp.awaited = false, int(kaddr)
+	}
+}
+
+// Frame latches the keys the machine sees for the frame about to run
+func (kp *keypad) Frame() {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	kp.frame = kp.down | kp.tapped
+	kp.tapped = 0
 }
 
-func (kp *keypad) Set(kaddr uint8) {
+// Await starts a wait for a key to be pressed and then released, as
+// the original interpreter did, keys already held when it starts
+// waiting have to be released and pressed again
+func (kp *keypad) Await() {
 	kp.mu.Lock()
 	defer kp.mu.Unlock()
-	kp.pressed[kaddr] = true
+	kp.waiting, kp.armed, kp.awaited = true, 0, -1
 }
 
-func (kp *keypad) Next() uint8 {
-	for {
-		kp.mu.Lock()
-		for kaddr, pressed := range kp.pressed {
-			if pressed {
-				kp.mu.Unlock()
-				return kaddr
-			}
-		}
-		kp.mu.Unlock()
+func (kp *keypad) Awaited() (uint8, bool) {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	if kp.awaited < 0 {
+		return 0, false
 	}
+	return uint8(kp.awaited), true
+}
+
+// State returns the keys down this frame as a bitmask, key n is bit n
+func (kp *keypad) State() uint16 {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	return kp.frame
+}
+
+// Restore sets the keys down this frame from a bitmask returned by
+// State, the keys held on the frontend take over from the next frame
+func (kp *keypad) Restore(state uint16) {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	kp.frame = state
 }
@@ -2,7 +2,6 @@ package keypad
 
 import (
 	"testing"
-	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -13,39 +12,94 @@ func Test_Create(t *testing.T) {
 }
 
 func Test_Clear(t *testing.T) {
-	keypad := &keypad{
-		pressed: map[byte]bool{
-			'a': true,
-		},
-	}
+	keypad := &keypad{down: 0x0002, frame: 0x0002}
 	keypad.Clear()
-	assert.False(t, keypad.pressed['a'])
+	assert.False(t, keypad.Get(0x1))
+	keypad.Frame()
+	assert.False(t, keypad.Get(0x1))
 }
 
 func Test_Get(t *testing.T) {
-	keypad := &keypad{
-		pressed: map[byte]bool{
-			'a': true,
-		},
-	}
-	assert.True(t, keypad.Get('a'))
-}
-
-func Test_Set(t *testing.T) {
-	keypad := &keypad{
-		pressed: map[byte]bool{},
-	}
-	keypad.Set('a')
-	assert.True(t, keypad.pressed['a'])
-}
-
-func Test_Next(t *testing.T) {
-	keypad := &keypad{
-		pressed: map[byte]bool{},
-	}
-	go func() {
-		assert.Equal(t, uint8('a'), keypad.Next())
-	}()
-	time.Sleep(2 * time.Second)
-	keypad.Set('a')
+	keypad := &keypad{frame: 0x0400}
+	assert.True(t, keypad.Get(0xA))
+	assert.False(t, keypad.Get(0xB))
+	assert.False(t, keypad.Get('a'))
+}
+
+func Test_Press(t *testing.T) {
+	keypad := &keypad{}
+	keypad.Press(0xA)
+	// keys are only seen from the next frame
+	assert.False(t, keypad.Get(0xA))
+	keypad.Frame()
+	assert.True(t, keypad.Get(0xA))
+	keypad.Frame()
+	assert.True(t, keypad.Get(0xA))
+}
+
+func Test_Release(t *testing.T) {
+	keypad := &keypad{}
+	keypad.Press(0xA)
+	keypad.Frame()
+	keypad.Release(0xA)
+	assert.True(t, keypad.Get(0xA))
+	keypad.Frame()
+	assert.False(t, keypad.Get(0xA))
+}
+
+func Test_Frame_tap(t *testing.T) {
+	keypad := &keypad{}
+	// pressed and released between frames
+	keypad.Press(0x3)
+	keypad.Release(0x3)
+	keypad.Frame()
+	assert.True(t, keypad.Get(0x3))
+	keypad.Frame()
+	assert.False(t, keypad.Get(0x3))
+}
+
+func Test_Await(t *testing.T) {
+	keypad := Create()
+	keypad.Press(0x1)
+	keypad.Await()
+	// held before the wait started
+	keypad.Release(0x1)
+	keypad.Press(0xA)
+	_, ok := keypad.Awaited()
+	assert.False(t, ok)
+
+	keypad.Release(0xA)
+	key, ok := keypad.Awaited()
+	assert.True(t, ok)
+	assert.Equal(t, uint8(0xA), key)
+
+	// a new wait forgets the last key
+	keypad.Await()
+	_, ok = keypad.Awaited()
+	assert.False(t, ok)
+}
+
+func Test_Await_clear(t *testing.T) {
+	keypad := Create()
+	keypad.Await()
+	keypad.Press(0x2)
+	keypad.Clear()
+	keypad.Release(0x2)
+	_, ok := keypad.Awaited()
+	assert.False(t, ok)
+}
+
+func Test_State(t *testing.T) {
+	keypad := &keypad{}
+	keypad.Press(0x1)
+	keypad.Press(0xF)
+	keypad.Frame()
+	assert.Equal(t, uint16(0x8002), keypad.State())
+
+	keypad.Restore(0x0010)
+	assert.False(t, keypad.Get(0x1))
+	assert.True(t, keypad.Get(0x4))
+	// the held keys come back on the next frame
+	keypad.Frame()
+	assert.Equal(t, uint16(0x8002), keypad.State())
 }
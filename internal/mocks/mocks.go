@@ -3,6 +3,8 @@ package mocks
 import (
 	"github.com/bchadwic/chip8/internal/display"
 	"github.com/bchadwic/chip8/internal/display/emit"
+	"github.com/bchadwic/chip8/internal/frontend"
+	"github.com/bchadwic/chip8/internal/speaker"
 )
 
 type TestDisplay struct {
@@ -14,10 +16,19 @@ type TestDisplay struct {
 	In_SetEmit           emit.Emit
 	In_SetRow, In_SetCol uint8
 
+	In_ScrollRows, In_ScrollCols int
+
+	In_SetResolutionRows, In_SetResolutionCols uint8
+
+	In_SelectPlanesMask uint8
+
+	In_RestoreState display.State
+
 	// outputs
 	Out_GetEmit                            emit.Emit
 	Out_PixelsPixels                       []display.Pixel
 	Out_WindowSizeInt1, Out_WindowSizeInt2 int
+	Out_SnapshotState                      display.State
 }
 
 func (td *TestDisplay) Clear() {
@@ -36,6 +47,20 @@ func (td *TestDisplay) Set(e emit.Emit, row, col uint8) {
 	td.In_SetCol = col
 }
 
+func (td *TestDisplay) Scroll(rows, cols int) {
+	td.In_ScrollRows = rows
+	td.In_ScrollCols = cols
+}
+
+func (td *TestDisplay) SetResolution(rows, cols uint8) {
+	td.In_SetResolutionRows = rows
+	td.In_SetResolutionCols = cols
+}
+
+func (td *TestDisplay) SelectPlanes(mask uint8) {
+	td.In_SelectPlanesMask = mask
+}
+
 func (td *TestDisplay) Pixels() []display.Pixel {
 	return td.Out_PixelsPixels
 }
@@ -43,3 +68,50 @@ func (td *TestDisplay) Pixels() []display.Pixel {
 func (td *TestDisplay) WindowSize() (int, int) {
 	return td.Out_WindowSizeInt1, td.Out_WindowSizeInt2
 }
+
+func (td *TestDisplay) Snapshot() display.State {
+	return td.Out_SnapshotState
+}
+
+func (td *TestDisplay) Restore(s display.State) {
+	td.In_RestoreState = s
+}
+
+type TestFrontend struct {
+	// inputs
+	In_OpenRows, In_OpenCols int
+	In_PresentCount          int
+	In_PlayCount             int
+	In_Close                 bool
+
+	// outputs
+	Out_OpenErr   error
+	Out_PollInput frontend.Input
+	Out_QuitChan  chan struct{}
+}
+
+func (tf *TestFrontend) Open(rows, cols int) error {
+	tf.In_OpenRows = rows
+	tf.In_OpenCols = cols
+	return tf.Out_OpenErr
+}
+
+func (tf *TestFrontend) Present(d display.Display) {
+	tf.In_PresentCount++
+}
+
+func (tf *TestFrontend) Play(s speaker.Speaker) {
+	tf.In_PlayCount++
+}
+
+func (tf *TestFrontend) Poll() frontend.Input {
+	return tf.Out_PollInput
+}
+
+func (tf *TestFrontend) Quit() <-chan struct{} {
+	return tf.Out_QuitChan
+}
+
+func (tf *TestFrontend) Close() {
+	tf.In_Close = true
+}
@@ -1,19 +1,37 @@
 package speaker
 
-import "sync"
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"sync"
+)
 
 type Speaker interface {
 	IsActive() bool
 	Set(bool)
+	Pattern() ([]uint8, uint8)
+	SetPattern(pattern []uint8)
+	SetPitch(pitch uint8)
 }
 
+const (
+	// xo-chip audio patterns are 128 one bit samples
+	PATTERN_SIZE  = 16
+	DEFAULT_PITCH = 64
+)
+
 type speaker struct {
-	active bool
-	mu     sync.Mutex
+	active  bool
+	pattern []uint8
+	pitch   uint8
+	mu      sync.Mutex
 }
 
 func Create() Speaker {
-	return &speaker{}
+	return &speaker{
+		pitch: DEFAULT_PITCH,
+	}
 }
 
 func (sp *speaker) IsActive() bool {
@@ -27,3 +45,72 @@ func (sp *speaker) Set(active bool) {
 	defer sp.mu.Unlock()
 	sp.active = active
 }
+
+// Pattern returns the audio pattern and pitch, the
+// pattern is nil unless a rom has loaded one
+func (sp *speaker) Pattern() ([]uint8, uint8) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if sp.pattern == nil {
+		return nil, sp.pitch
+	}
+	pattern := make([]uint8, len(sp.pattern))
+	copy(pattern, sp.pattern)
+	return pattern, sp.pitch
+}
+
+// SetPattern replaces the audio pattern, nil restores the default beep
+func (sp *speaker) SetPattern(pattern []uint8) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if pattern == nil {
+		sp.pattern = nil
+		return
+	}
+	sp.pattern = make([]uint8, PATTERN_SIZE)
+	copy(sp.pattern, pattern)
+}
+
+func (sp *speaker) SetPitch(pitch uint8) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.pitch = pitch
+}
+
+// Rate returns the number of pattern bits played per second for pitch
+func Rate(pitch uint8) int {
+	return int(4000 * math.Pow(2, (float64(pitch)-64)/48))
+}
+
+// Wave renders the pattern played at pitch as an 8 bit mono wav
+// file, the pattern is repeated to fill the given number of seconds
+func Wave(pattern []uint8, pitch uint8, seconds float64) []byte {
+	rate := Rate(pitch)
+	samples := make([]uint8, int(float64(rate)*seconds))
+	bits := len(pattern) * 8
+	for i := range samples {
+		b := i % bits
+		if pattern[b/8]&(0x80>>(b%8)) != 0 {
+			samples[i] = 0xC0
+		} else {
+			samples[i] = 0x40
+		}
+	}
+
+	var buf bytes.Buffer
+	le := binary.LittleEndian
+	buf.WriteString("RIFF")
+	binary.Write(&buf, le, uint32(36+len(samples)))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, le, uint32(16)) // fmt chunk size
+	binary.Write(&buf, le, uint16(1))  // pcm
+	binary.Write(&buf, le, uint16(1))  // mono
+	binary.Write(&buf, le, uint32(rate))
+	binary.Write(&buf, le, uint32(rate)) // byte rate
+	binary.Write(&buf, le, uint16(1))    // block align
+	binary.Write(&buf, le, uint16(8))    // bits per sample
+	buf.WriteString("data")
+	binary.Write(&buf, le, uint32(len(samples)))
+	buf.Write(samples)
+	return buf.Bytes()
+}
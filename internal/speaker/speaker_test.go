@@ -23,3 +23,36 @@ func Test_Set(t *testing.T) {
 	speaker.Set(true)
 	assert.True(t, speaker.IsActive())
 }
+
+func Test_SetPattern(t *testing.T) {
+	speaker := Create()
+	pattern, pitch := speaker.Pattern()
+	assert.Nil(t, pattern)
+	assert.Equal(t, uint8(DEFAULT_PITCH), pitch)
+
+	speaker.SetPattern([]uint8{0xFF, 0x00})
+	speaker.SetPitch(112)
+	pattern, pitch = speaker.Pattern()
+	assert.Equal(t, PATTERN_SIZE, len(pattern))
+	assert.Equal(t, uint8(0xFF), pattern[0])
+	assert.Equal(t, uint8(112), pitch)
+
+	speaker.SetPattern(nil)
+	pattern, _ = speaker.Pattern()
+	assert.Nil(t, pattern)
+}
+
+func Test_Rate(t *testing.T) {
+	assert.Equal(t, 4000, Rate(64))
+	assert.Equal(t, 8000, Rate(112))
+}
+
+func Test_Wave(t *testing.T) {
+	pattern := make([]uint8, PATTERN_SIZE)
+	pattern[0] = 0x80
+	wave := Wave(pattern, 64, 0.5)
+	assert.Equal(t, "RIFF", string(wave[:4]))
+	assert.Equal(t, 44+2000, len(wave))
+	assert.Equal(t, uint8(0xC0), wave[44])
+	assert.Equal(t, uint8(0x40), wave[45])
+}
@@ -0,0 +1,316 @@
+// Package terminal is a frontend that shows the emulator in a terminal
+// rather than a window. Pixels are drawn two rows at a time with half block
+// characters in ansi colors, keys are read from the terminal in raw mode,
+// and the speaker rings the terminal bell.
+package terminal
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bchadwic/chip8/internal/display"
+	"github.com/bchadwic/chip8/internal/display/emit"
+	"github.com/bchadwic/chip8/internal/frontend"
+	"github.com/bchadwic/chip8/internal/hotkey"
+	"github.com/bchadwic/chip8/internal/keymap"
+	"github.com/bchadwic/chip8/internal/speaker"
+)
+
+const (
+	// upper half block, the top pixel is the foreground color
+	// and the bottom pixel the background color
+	HALF_BLOCK = "▀"
+
+	// ctrl+c, in raw mode it is read like any other key
+	// and quits the frontend rather than interrupting
+	INTERRUPT = 0x03
+	BACKSPACE = 0x7F
+)
+
+// escape sequences sent for the function keys used as hotkeys
+var hotkeys = map[string]hotkey.Hotkey{
+	"\x1b[15~": hotkey.SAVE_STATE,
+	"\x1b[20~": hotkey.LOAD_STATE,
+	"\x1b[21~": hotkey.RECORD,
+	"\x1b[24~": hotkey.SCREENSHOT,
+}
+
+// escape sequences and control characters sent for named keys
+var namedKeys = map[string]string{
+	"\x1b[A": keymap.UP,
+	"\x1b[B": keymap.DOWN,
+	"\x1b[C": keymap.RIGHT,
+	"\x1b[D": keymap.LEFT,
+	"\r":     keymap.ENTER,
+	"\t":     keymap.TAB,
+}
+
+type terminalContext struct {
+	in  *os.File
+	out io.Writer
+
+	// colors indexed by the planes a pixel is on in
+	palette  [4]color.Color
+	keyboard keymap.Keymap
+
+	// the last screen drawn, it is only redrawn when it changes
+	last []byte
+	// screens waiting to be written, only the latest is kept
+	screens chan []byte
+	// whether the speaker was active on the last frame
+	beeping bool
+
+	// held while writing so the bell does not land mid screen
+	outMu sync.Mutex
+	// everything below is shared with the goroutine reading keys
+	mu sync.Mutex
+	// stty settings to put back once the emulator exits,
+	// empty once they have been
+	saved string
+	// input waiting to be polled
+	input frontend.Input
+	// terminals only send keys as they are typed, so
+	// keys are held until they stop repeating
+	typed frontend.Typed
+	// keys held as of the last poll
+	held uint16
+	// closed once ctrl+c is pressed
+	quit      chan struct{}
+	closeOnce sync.Once
+}
+
+func Create() *terminalContext {
+	return &terminalContext{
+		in:       os.Stdin,
+		out:      os.Stdout,
+		palette:  display.Palette("white"),
+		keyboard: keymap.QWERTY,
+		screens:  make(chan []byte, 1),
+		quit:     make(chan struct{}),
+	}
+}
+
+func (tc *terminalContext) DisplaySettings(color string) *terminalContext {
+	tc.palette = display.Palette(color)
+	return tc
+}
+
+func (tc *terminalContext) KeypadSettings(keyboard keymap.Keymap) *terminalContext {
+	tc.keyboard = keyboard
+	return tc
+}
+
+// Open puts the terminal in raw mode, until Close is called ctrl+c
+// no longer interrupts the process and instead quits the frontend
+func (tc *terminalContext) Open(rows, cols int) error {
+	saved, err := stty("-g")
+	if err != nil {
+		return fmt.Errorf("could not read terminal settings: %v", err)
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return fmt.Errorf("could not put terminal in raw mode: %v", err)
+	}
+	tc.mu.Lock()
+	tc.saved = strings.TrimSpace(saved)
+	tc.mu.Unlock()
+	// clear the screen and hide the cursor
+	tc.write([]byte("\x1b[2J\x1b[?25l"))
+
+	go tc.readKeyboard()
+	go tc.writeScreens()
+	return nil
+}
+
+// Close puts the terminal back the way it was before Open,
+// it is safe to call more than once
+func (tc *terminalContext) Close() {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if tc.saved == "" {
+		return
+	}
+	tc.write([]byte("\x1b[0m\x1b[?25h\r\n"))
+	if _, err := stty(tc.saved); err != nil {
+		log.Printf("could not restore terminal: %v", err)
+	}
+	tc.saved = ""
+}
+
+// Present queues the display to be drawn if it changed, terminals
+// can be slow so the screen is written from its own goroutine
+func (tc *terminalContext) Present(d display.Display) {
+	rows, cols := d.WindowSize()
+	screen := render(d.Pixels(), rows, cols, tc.palette)
+	if bytes.Equal(screen, tc.last) {
+		return
+	}
+	tc.last = screen
+	// replace a screen that has not been written yet
+	select {
+	case <-tc.screens:
+	default:
+	}
+	tc.screens <- screen
+}
+
+// Play rings the bell once each time the speaker starts
+func (tc *terminalContext) Play(s speaker.Speaker) {
+	active := s.IsActive()
+	if active && !tc.beeping {
+		tc.write([]byte{'\a'})
+	}
+	tc.beeping = active
+}
+
+func (tc *terminalContext) Poll() frontend.Input {
+	return tc.poll(time.Now())
+}
+
+func (tc *terminalContext) poll(now time.Time) frontend.Input {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	held := tc.typed.Held(now)
+	tc.input.Keys = frontend.Changes(tc.held, held)
+	tc.held = held
+	in := tc.input
+	tc.input = frontend.Input{}
+	return in
+}
+
+func (tc *terminalContext) Quit() <-chan struct{} {
+	return tc.quit
+}
+
+func (tc *terminalContext) write(b []byte) {
+	tc.outMu.Lock()
+	defer tc.outMu.Unlock()
+	tc.out.Write(b)
+}
+
+func (tc *terminalContext) writeScreens() {
+	for screen := range tc.screens {
+		tc.write(screen)
+	}
+}
+
+// render draws pixels two rows per line, starting from the top left
+// of the terminal, rows are ended with \r\n as raw mode does not
+// return the cursor on \n
+func render(pixels []display.Pixel, rows, cols int, palette [4]color.Color) []byte {
+	var b bytes.Buffer
+	b.WriteString("\x1b[H")
+	colorOf := func(pixel display.Pixel) color.Color {
+		if pixel.Status != emit.ON {
+			return palette[0]
+		}
+		return palette[pixel.Color]
+	}
+	for row := 0; row < rows; row += 2 {
+		var fg, bg color.Color
+		for col := 0; col < cols; col++ {
+			top := colorOf(pixels[row*cols+col])
+			bottom := palette[0]
+			if row+1 < rows {
+				bottom = colorOf(pixels[(row+1)*cols+col])
+			}
+			// colors are only sent when they change
+			if top != fg {
+				fg = top
+				r, g, bl, _ := fg.RGBA()
+				fmt.Fprintf(&b, "\x1b[38;2;%d;%d;%dm", r>>8, g>>8, bl>>8)
+			}
+			if bottom != bg {
+				bg = bottom
+				r, g, bl, _ := bg.RGBA()
+				fmt.Fprintf(&b, "\x1b[48;2;%d;%d;%dm", r>>8, g>>8, bl>>8)
+			}
+			b.WriteString(HALF_BLOCK)
+		}
+		b.WriteString("\x1b[0m\r\n")
+	}
+	return b.Bytes()
+}
+
+func (tc *terminalContext) readKeyboard() {
+	buf := make([]byte, 64)
+	for {
+		n, err := tc.in.Read(buf)
+		if err != nil {
+			log.Printf("could not read keyboard: %v", err)
+			return
+		}
+		tc.mu.Lock()
+		interrupted := tc.keys(buf[:n], time.Now())
+		tc.mu.Unlock()
+		if interrupted {
+			tc.closeOnce.Do(func() { close(tc.quit) })
+			return
+		}
+	}
+}
+
+// keys queues input read from the terminal at now, reporting whether ctrl+c was pressed
+func (tc *terminalContext) keys(in []byte, now time.Time) bool {
+	for len(in) > 0 {
+		if in[0] == '\x1b' {
+			seq := escape(in)
+			if h, ok := hotkeys[string(seq)]; ok {
+				tc.input.Hotkeys = append(tc.input.Hotkeys, h)
+			}
+			tc.named(string(seq), now)
+			in = in[len(seq):]
+			continue
+		}
+		switch c := in[0]; c {
+		case INTERRUPT:
+			return true
+		case BACKSPACE:
+			tc.input.Hotkeys = append(tc.input.Hotkeys, hotkey.REWIND)
+		case '\r', '\t':
+			tc.named(string(c), now)
+		default:
+			if key, ok := tc.keyboard.Key(rune(c)); ok {
+				tc.typed.Type(key, now)
+			}
+		}
+		in = in[1:]
+	}
+	return false
+}
+
+// named types the key bound to a named key sent as seq, if any
+func (tc *terminalContext) named(seq string, now time.Time) {
+	if key, ok := tc.keyboard[namedKeys[seq]]; ok {
+		tc.typed.Type(key, now)
+	}
+}
+
+// escape returns the escape sequence at the start of in, sequences
+// are ESC [ followed by parameters and end with a letter or ~
+func escape(in []byte) []byte {
+	if len(in) < 2 || in[1] != '[' {
+		return in[:1]
+	}
+	for i := 2; i < len(in); i++ {
+		if c := in[i]; c == '~' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') {
+			return in[:i+1]
+		}
+	}
+	return in
+}
+
+// stty runs stty on the terminal, returning what it printed
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
@@ -0,0 +1,104 @@
+package terminal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bchadwic/chip8/internal/display"
+	"github.com/bchadwic/chip8/internal/display/emit"
+	"github.com/bchadwic/chip8/internal/frontend"
+	"github.com/bchadwic/chip8/internal/hotkey"
+	"github.com/bchadwic/chip8/internal/keymap"
+	"github.com/bchadwic/chip8/internal/speaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_render(t *testing.T) {
+	d := display.Create(3, 2)
+	d.Set(emit.ON, 0, 0)
+	d.Set(emit.ON, 1, 1)
+	d.Set(emit.ON, 2, 0)
+	screen := render(d.Pixels(), 3, 2, display.Palette("white"))
+
+	white, black := "255;255;255", "0;0;0"
+	assert.Equal(t, "\x1b[H"+
+		// top on, bottom off then top off, bottom on
+		"\x1b[38;2;"+white+"m\x1b[48;2;"+black+"m"+HALF_BLOCK+
+		"\x1b[38;2;"+black+"m\x1b[48;2;"+white+"m"+HALF_BLOCK+"\x1b[0m\r\n"+
+		// the last row has nothing below it
+		"\x1b[38;2;"+white+"m\x1b[48;2;"+black+"m"+HALF_BLOCK+
+		"\x1b[38;2;"+black+"m"+HALF_BLOCK+"\x1b[0m\r\n", string(screen))
+}
+
+func Test_keys(t *testing.T) {
+	tc := Create().KeypadSettings(keymap.QWERTY)
+
+	now := time.Now()
+
+	assert.False(t, tc.keys([]byte("w\x1b[15~\x1b[A\x7fzp"), now))
+	assert.Equal(t, frontend.Input{
+		Keys:    []frontend.KeyEvent{{Key: 0x5, Down: true}, {Key: 0xA, Down: true}},
+		Hotkeys: []hotkey.Hotkey{hotkey.SAVE_STATE, hotkey.REWIND},
+	}, tc.poll(now))
+	assert.Equal(t, frontend.Input{}, tc.poll(now))
+
+	// w repeats while it is held, z is let go
+	tc.keys([]byte("w"), now.Add(frontend.REPEAT_DELAY-frontend.REPEAT_INTERVAL/2))
+	assert.Equal(t, frontend.Input{
+		Keys: []frontend.KeyEvent{{Key: 0xA, Down: false}},
+	}, tc.poll(now.Add(frontend.REPEAT_DELAY)))
+
+	assert.True(t, tc.keys([]byte{'q', INTERRUPT}, now))
+}
+
+func Test_keys_named(t *testing.T) {
+	tc := Create().KeypadSettings(keymap.Keymap{keymap.UP: 0x5, keymap.ENTER: 0x6, keymap.SPACE: 0x4})
+	now := time.Now()
+
+	tc.keys([]byte("\x1b[A\r \x1b[Bw"), now)
+	assert.Equal(t, []frontend.KeyEvent{
+		{Key: 0x4, Down: true}, {Key: 0x5, Down: true}, {Key: 0x6, Down: true},
+	}, tc.poll(now).Keys)
+}
+
+func Test_escape(t *testing.T) {
+	assert.Equal(t, "\x1b[24~", string(escape([]byte("\x1b[24~w"))))
+	assert.Equal(t, "\x1b[A", string(escape([]byte("\x1b[Ax"))))
+	assert.Equal(t, "\x1b", string(escape([]byte("\x1bw"))))
+	assert.Equal(t, "\x1b[2", string(escape([]byte("\x1b[2"))))
+}
+
+func Test_Play(t *testing.T) {
+	var out bytes.Buffer
+	s := speaker.Create()
+	tc := Create()
+	tc.out = &out
+	s.Set(true)
+	tc.Play(s)
+	tc.Play(s)
+	s.Set(false)
+	tc.Play(s)
+	s.Set(true)
+	tc.Play(s)
+	assert.Equal(t, 2, strings.Count(out.String(), "\a"))
+}
+
+func Test_Present(t *testing.T) {
+	tc := Create()
+	d := display.Create(32, 64)
+	tc.Present(d)
+	assert.Len(t, tc.screens, 1)
+	// an unchanged screen is not drawn again
+	<-tc.screens
+	tc.Present(d)
+	assert.Len(t, tc.screens, 0)
+
+	d.Set(emit.ON, 0, 0)
+	tc.Present(d)
+	d.Set(emit.ON, 0, 1)
+	tc.Present(d)
+	assert.Len(t, tc.screens, 1)
+	assert.Equal(t, tc.last, <-tc.screens)
+}
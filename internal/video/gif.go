@@ -0,0 +1,70 @@
+package video
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+
+	"github.com/bchadwic/chip8/internal/display"
+)
+
+// gif delays are in hundredths of a second
+const CENTISECONDS = 100
+
+// most viewers slow delays shorter than this down to a tenth
+// of a second, frames that would be shorter are dropped
+const MIN_GIF_DELAY = 2
+
+type gifRecorder struct {
+	w       io.Writer
+	scale   int
+	palette [4]color.Color
+
+	images []*image.Paletted
+	// frames each image is shown for
+	frames []int
+}
+
+// GIF records an animated gif, which is only written to w once the
+// recorder is closed. Repeated frames are stored once and shown longer.
+func GIF(w io.Writer, scale int, palette [4]color.Color) Recorder {
+	return &gifRecorder{w: w, scale: scale, palette: palette}
+}
+
+func (g *gifRecorder) Frame(d display.Display) error {
+	img := display.Image(d, g.scale, g.palette)
+	if n := len(g.images); n > 0 && sameImage(g.images[n-1], img) {
+		g.frames[n-1]++
+		return nil
+	}
+	g.images = append(g.images, img)
+	g.frames = append(g.frames, 1)
+	return nil
+}
+
+func (g *gifRecorder) Close() error {
+	anim := &gif.GIF{}
+	// delays are worked out from when frames start so rounding
+	// does not add up, brief frames are folded into the next one
+	start, elapsed := 0, 0
+	for i, img := range g.images {
+		elapsed += g.frames[i]
+		delay := elapsed*CENTISECONDS/FPS - start*CENTISECONDS/FPS
+		if delay < MIN_GIF_DELAY && i < len(g.images)-1 {
+			continue
+		}
+		anim.Image = append(anim.Image, img)
+		anim.Delay = append(anim.Delay, delay)
+		start = elapsed
+	}
+	if len(anim.Image) == 0 {
+		return nil
+	}
+	return gif.EncodeAll(g.w, anim)
+}
+
+func sameImage(a, b *image.Paletted) bool {
+	return a.Rect == b.Rect && bytes.Equal(a.Pix, b.Pix)
+}
@@ -0,0 +1,54 @@
+// Package video records the display a frame at a time, either as an
+// animated gif or as a raw y4m stream that video tools can encode.
+package video
+
+import (
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bchadwic/chip8/internal/display"
+)
+
+// frames per second recordings are captured at, one per emulator frame
+const FPS = 60
+
+// Recorder captures a frame of the display every time Frame is called
+type Recorder interface {
+	Frame(d display.Display) error
+	// Close finishes the recording, the recorder can not be used after
+	Close() error
+}
+
+// Create records to a file at path, the format is picked from its
+// extension, .gif or .y4m
+func Create(path string, scale int, palette [4]color.Color) (Recorder, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != ".gif" && ext != ".y4m" {
+		return nil, fmt.Errorf("unknown recording format %q, expected .gif or .y4m", ext)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if ext == ".gif" {
+		return &closer{GIF(f, scale, palette), f}, nil
+	}
+	return &closer{Y4M(f, scale, palette), f}, nil
+}
+
+// closer closes the file a recorder writes to once it is finished
+type closer struct {
+	Recorder
+	f *os.File
+}
+
+func (c *closer) Close() error {
+	err := c.Recorder.Close()
+	if cerr := c.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
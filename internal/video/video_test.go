@@ -0,0 +1,99 @@
+package video
+
+import (
+	"bytes"
+	"image/gif"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bchadwic/chip8/internal/display"
+	"github.com/bchadwic/chip8/internal/display/emit"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Create(t *testing.T) {
+	_, err := Create(filepath.Join(t.TempDir(), "clip.mp4"), 1, display.Palette("white"))
+	assert.Error(t, err)
+
+	r, err := Create(filepath.Join(t.TempDir(), "clip.GIF"), 1, display.Palette("white"))
+	assert.NoError(t, err)
+	assert.NoError(t, r.Close())
+}
+
+func Test_GIF(t *testing.T) {
+	var b bytes.Buffer
+	d := display.Create(32, 64)
+	r := GIF(&b, 2, display.Palette("white"))
+	for f := 0; f < 60; f++ {
+		// the screen changes after half a second and
+		// again for a single frame at the end
+		if f == 30 || f == 59 {
+			d.Set(emit.ON, uint8(f%32), 0)
+		}
+		assert.NoError(t, r.Frame(d))
+	}
+	assert.NoError(t, r.Close())
+
+	anim, err := gif.DecodeAll(&b)
+	assert.NoError(t, err)
+	assert.Len(t, anim.Image, 3)
+	assert.Equal(t, []int{50, 48, 2}, anim.Delay)
+	assert.Equal(t, 128, anim.Config.Width)
+}
+
+func Test_GIF_brief(t *testing.T) {
+	var b bytes.Buffer
+	d := display.Create(32, 64)
+	r := GIF(&b, 1, display.Palette("white"))
+	// a frame too short to show is folded into the next
+	assert.NoError(t, r.Frame(d))
+	d.Set(emit.ON, 0, 0)
+	for f := 0; f < 3; f++ {
+		assert.NoError(t, r.Frame(d))
+	}
+	assert.NoError(t, r.Close())
+
+	anim, err := gif.DecodeAll(&b)
+	assert.NoError(t, err)
+	assert.Len(t, anim.Image, 1)
+	assert.Equal(t, []int{6}, anim.Delay)
+}
+
+func Test_Y4M(t *testing.T) {
+	var b bytes.Buffer
+	d := display.Create(32, 64)
+	d.Set(emit.ON, 0, 1)
+	r := Y4M(&b, 1, display.Palette("white"))
+	assert.NoError(t, r.Frame(d))
+	d.SetResolution(64, 128)
+	assert.NoError(t, r.Frame(d))
+	assert.NoError(t, r.Close())
+
+	header := "YUV4MPEG2 W64 H32 F60:1 Ip A1:1 C444\n"
+	frame := len("FRAME\n") + 3*64*32
+	assert.True(t, strings.HasPrefix(b.String(), header))
+	assert.Equal(t, len(header)+2*frame, b.Len())
+	// the pixel that is on is white
+	y := b.Bytes()[len(header)+len("FRAME\n"):]
+	assert.Equal(t, uint8(0), y[0])
+	assert.Equal(t, uint8(255), y[1])
+}
+
+func Test_Y4M_resolution(t *testing.T) {
+	var b bytes.Buffer
+	d := display.Create(32, 64)
+	r := Y4M(&b, 2, display.Palette("white"))
+	assert.NoError(t, r.Frame(d))
+	// hires pixels are a single pixel of the stream
+	d.SetResolution(64, 128)
+	d.Set(emit.ON, 0, 1)
+	assert.NoError(t, r.Frame(d))
+	assert.NoError(t, r.Close())
+
+	header := "YUV4MPEG2 W128 H64 F60:1 Ip A1:1 C444\n"
+	frame := len("FRAME\n") + 3*128*64
+	assert.Equal(t, len(header)+2*frame, b.Len())
+	y := b.Bytes()[len(header)+frame+len("FRAME\n"):]
+	assert.Equal(t, []uint8{0, 255, 0}, y[:3])
+}
@@ -0,0 +1,79 @@
+package video
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+
+	"github.com/bchadwic/chip8/internal/display"
+	"github.com/bchadwic/chip8/internal/display/emit"
+)
+
+type y4mRecorder struct {
+	w     *bufio.Writer
+	scale int
+	// y, cb and cr of each palette color
+	ycbcr [4][3]uint8
+
+	rows, cols int
+	// planes of the frame being written
+	planes [3][]uint8
+}
+
+// Y4M records an uncompressed y4m stream with full resolution color,
+// every frame is written as it is captured. The resolution is fixed by
+// the first frame, later frames at other resolutions are scaled to fit.
+func Y4M(w io.Writer, scale int, palette [4]color.Color) Recorder {
+	y := &y4mRecorder{w: bufio.NewWriter(w), scale: scale}
+	for i, c := range palette {
+		r, g, b, _ := c.RGBA()
+		cy, cb, cr := color.RGBToYCbCr(uint8(r>>8), uint8(g>>8), uint8(b>>8))
+		y.ycbcr[i] = [3]uint8{cy, cb, cr}
+	}
+	return y
+}
+
+func (y *y4mRecorder) Frame(d display.Display) error {
+	rows, cols := d.WindowSize()
+	if y.rows == 0 {
+		y.rows, y.cols = rows*y.scale, cols*y.scale
+		_, err := fmt.Fprintf(y.w, "YUV4MPEG2 W%d H%d F%d:1 Ip A1:1 C444\n", y.cols, y.rows, FPS)
+		if err != nil {
+			return err
+		}
+		for p := range y.planes {
+			y.planes[p] = make([]uint8, y.rows*y.cols)
+		}
+	}
+
+	// every pixel of the stream is colored by the display pixel it falls
+	// on, so frames at other resolutions are stretched or squeezed to fit
+	pixels := d.Pixels()
+	for r := 0; r < y.rows; r++ {
+		for col := 0; col < y.cols; col++ {
+			pixel := pixels[r*rows/y.rows*cols+col*cols/y.cols]
+			c := y.ycbcr[0]
+			if pixel.Status == emit.ON {
+				c = y.ycbcr[pixel.Color]
+			}
+			for p := range y.planes {
+				y.planes[p][r*y.cols+col] = c[p]
+			}
+		}
+	}
+
+	if _, err := y.w.WriteString("FRAME\n"); err != nil {
+		return err
+	}
+	for _, plane := range y.planes {
+		if _, err := y.w.Write(plane); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (y *y4mRecorder) Close() error {
+	return y.w.Flush()
+}
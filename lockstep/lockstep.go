@@ -0,0 +1,193 @@
+// Package lockstep runs two machines side by side an instruction at a
+// time from the same state and input, stopping at the first point
+// where they disagree. Comparing the interpreter against a quirk
+// variant, or against another core, catches changes to instruction
+// handlers that alter behavior.
+package lockstep
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bchadwic/chip8/emulator"
+	"github.com/bchadwic/chip8/internal/display"
+	"github.com/bchadwic/chip8/internal/display/emit"
+)
+
+// instructions kept to show what led up to a divergence
+const DEFAULT_WINDOW = 16
+
+// Runner drives two machines in lockstep. Both machines should have
+// the same rom loaded, the same seed, and keypads fed the same input,
+// such as two players of the same movie.
+type Runner struct {
+	A, B emulator.Machine
+	// instructions shown leading up to a divergence
+	Window int
+
+	// recent instructions executed by A, oldest first
+	recent []string
+	steps  int
+}
+
+// Divergence describes the first difference between the machines
+type Divergence struct {
+	// frame and instruction count the machines diverged on
+	Frame, Step int
+	// what differs, such as "V3 is 0x02 in a, 0x03 in b"
+	What string
+	// instructions executed by A leading up to the divergence
+	Trace []string
+}
+
+func (d *Divergence) Error() string {
+	return fmt.Sprintf("machines diverged after %d instructions on frame %d: %s", d.Step, d.Frame, d.What)
+}
+
+// Report describes the divergence along with the instructions leading up to it
+func (d *Divergence) Report() string {
+	var b strings.Builder
+	b.WriteString(d.Error())
+	b.WriteString("\nrecent instructions of a:\n")
+	for _, line := range d.Trace {
+		b.WriteString("  " + line + "\n")
+	}
+	return b.String()
+}
+
+func Create(a, b emulator.Machine) *Runner {
+	return &Runner{A: a, B: b, Window: DEFAULT_WINDOW}
+}
+
+// Run executes up to frames frames, returning the first divergence or
+// nil if the machines agreed throughout. Machines faulting in the same
+// way end the run early without a divergence.
+func (r *Runner) Run(frames int) *Divergence {
+	if d := r.compare(0, true); d != nil {
+		return d
+	}
+	for f := 1; f <= frames; f++ {
+		na, nb := r.A.Tick(), r.B.Tick()
+		if na != nb {
+			return r.diverged(f, fmt.Sprintf("frame is %d instructions in a, %d in b", na, nb))
+		}
+		for n := 0; n < na; n++ {
+			if r.A.Halted() || r.B.Halted() {
+				if r.A.Halted() != r.B.Halted() {
+					return r.diverged(f, fmt.Sprintf("halted is %t in a, %t in b", r.A.Halted(), r.B.Halted()))
+				}
+				return nil
+			}
+			r.remember()
+			inst := r.opcode()
+			errA, errB := r.A.Step(), r.B.Step()
+			r.steps++
+			if fmt.Sprint(errA) != fmt.Sprint(errB) {
+				return r.diverged(f, fmt.Sprintf("step returned %v in a, %v in b", errA, errB))
+			}
+			if errA != nil {
+				return nil
+			}
+			// the display only changes on 0 and D class instructions,
+			// so it is compared then and at the end of every frame
+			display := inst&emulator.N1_MASK == emulator.CLS_OR_RET || inst&emulator.N1_MASK == emulator.DRW_VX_VY_N
+			if d := r.compare(f, display || n == na-1); d != nil {
+				return d
+			}
+		}
+	}
+	return nil
+}
+
+// remember records the instruction A is about to execute
+func (r *Runner) remember() {
+	pc := r.A.PC()
+	var code []uint8
+	for o := uint16(0); o < 4 && int(pc+o) < r.A.MemorySize(); o++ {
+		code = append(code, r.A.Memory(pc+o))
+	}
+	line := fmt.Sprintf("%03X  ??", pc)
+	if len(code) > 0 {
+		mnemonic, size := emulator.Disassemble(code)
+		line = fmt.Sprintf("%03X  %-9X  %s", pc, code[:size], mnemonic)
+	}
+	r.recent = append(r.recent, line)
+	if len(r.recent) > r.Window {
+		r.recent = r.recent[len(r.recent)-r.Window:]
+	}
+}
+
+// opcode returns the instruction A is about to execute
+func (r *Runner) opcode() uint16 {
+	pc := r.A.PC()
+	if int(pc)+1 >= r.A.MemorySize() {
+		return 0
+	}
+	return uint16(r.A.Memory(pc))<<8 | uint16(r.A.Memory(pc+1))
+}
+
+func (r *Runner) diverged(frame int, what string) *Divergence {
+	trace := make([]string, len(r.recent))
+	copy(trace, r.recent)
+	return &Divergence{Frame: frame, Step: r.steps, What: what, Trace: trace}
+}
+
+// compare checks the cpu and memory, and the display when asked to
+func (r *Runner) compare(frame int, display bool) *Divergence {
+	a, b := r.A, r.B
+	for x := uint8(0); x < emulator.REGISTERS; x++ {
+		if a.Register(x) != b.Register(x) {
+			return r.diverged(frame, fmt.Sprintf("V%X is 0x%02X in a, 0x%02X in b", x, a.Register(x), b.Register(x)))
+		}
+	}
+	values := []struct {
+		name string
+		a, b int
+	}{
+		{"PC", int(a.PC()), int(b.PC())},
+		{"I", int(a.I()), int(b.I())},
+		{"SP", int(a.SP()), int(b.SP())},
+		{"DT", int(a.DT()), int(b.DT())},
+		{"ST", int(a.ST()), int(b.ST())},
+		{"memory size", a.MemorySize(), b.MemorySize()},
+	}
+	for _, v := range values {
+		if v.a != v.b {
+			return r.diverged(frame, fmt.Sprintf("%s is 0x%X in a, 0x%X in b", v.name, v.a, v.b))
+		}
+	}
+	stackA, stackB := a.Stack(), b.Stack()
+	for i := 0; i < int(a.SP()) && i < len(stackA) && i < len(stackB); i++ {
+		if stackA[i] != stackB[i] {
+			return r.diverged(frame, fmt.Sprintf("stack[%d] is 0x%03X in a, 0x%03X in b", i, stackA[i], stackB[i]))
+		}
+	}
+	for addr := 0; addr < a.MemorySize(); addr++ {
+		if va, vb := a.Memory(uint16(addr)), b.Memory(uint16(addr)); va != vb {
+			return r.diverged(frame, fmt.Sprintf("memory 0x%03X is 0x%02X in a, 0x%02X in b", addr, va, vb))
+		}
+	}
+	if !display {
+		return nil
+	}
+
+	rowsA, colsA := a.Display().WindowSize()
+	rowsB, colsB := b.Display().WindowSize()
+	if rowsA != rowsB || colsA != colsB {
+		return r.diverged(frame, fmt.Sprintf("display is %dx%d in a, %dx%d in b", colsA, rowsA, colsB, rowsB))
+	}
+	pixelsB := b.Display().Pixels()
+	for i, p := range a.Display().Pixels() {
+		if p != pixelsB[i] {
+			return r.diverged(frame, fmt.Sprintf("pixel %d,%d is %s in a, %s in b", p.Col, p.Row, describe(p), describe(pixelsB[i])))
+		}
+	}
+	return nil
+}
+
+func describe(p display.Pixel) string {
+	if p.Status == emit.OFF {
+		return "off"
+	}
+	return fmt.Sprintf("on in planes %02b", p.Color)
+}
@@ -0,0 +1,63 @@
+package lockstep
+
+import (
+	"os"
+	"testing"
+
+	"github.com/bchadwic/chip8/emulator"
+	"github.com/stretchr/testify/assert"
+)
+
+func testMachine(t *testing.T, rom []uint8, quirks emulator.Quirks) emulator.Machine {
+	m := emulator.Create(&emulator.EmulatorSettings{Headless: true, Seed: 1, Quirks: quirks})
+	assert.NoError(t, m.LoadROM(rom))
+	return m
+}
+
+// the interpreter agrees with itself, roms waiting on FX0A
+// are left out since nothing presses a key
+func Test_Run(t *testing.T) {
+	for _, name := range []string{"ibm", "maze", "pong", "stars", "tetris"} {
+		rom, err := os.ReadFile("../roms/" + name + ".ch8")
+		assert.NoError(t, err)
+		r := Create(testMachine(t, rom, emulator.Quirks{}), testMachine(t, rom, emulator.Quirks{}))
+		assert.Nil(t, r.Run(60), name)
+	}
+}
+
+func Test_Run_diverged(t *testing.T) {
+	rom := []uint8{
+		0x60, 0x01, // 0x200: LD V0, 0x01
+		0x61, 0x04, // 0x202: LD V1, 0x04
+		0x80, 0x16, // 0x204: SHR V0, V1
+		0x12, 0x06, // 0x206: JMP 0x206
+	}
+	r := Create(testMachine(t, rom, emulator.Quirks{}), testMachine(t, rom, emulator.Quirks{Shift: true}))
+	r.Window = 2
+	d := r.Run(1)
+	assert.NotNil(t, d)
+	assert.Equal(t, 1, d.Frame)
+	assert.Equal(t, 3, d.Step)
+	assert.Equal(t, "V0 is 0x00 in a, 0x02 in b", d.What)
+	assert.Equal(t, []string{
+		"202  6104       LD V1, 0x04",
+		"204  8016       SHR V0, V1",
+	}, d.Trace)
+	assert.Contains(t, d.Report(), "machines diverged after 3 instructions on frame 1")
+}
+
+func Test_Run_display(t *testing.T) {
+	rom := []uint8{
+		0x60, 0x3E, // 0x200: LD V0, 0x3E
+		0xA2, 0x0A, // 0x202: LD I, 0x20A
+		0xD0, 0x01, // 0x204: DRW V0, V0, 1
+		0x12, 0x06, // 0x206: JMP 0x206
+		0x00, 0x00,
+		0xFF, // 0x20A: sprite
+	}
+	// the sprite runs off the right edge, wrapping in b
+	r := Create(testMachine(t, rom, emulator.Quirks{}), testMachine(t, rom, emulator.Quirks{Wrap: true}))
+	d := r.Run(1)
+	assert.NotNil(t, d)
+	assert.Equal(t, "pixel 0,30 is off in a, on in planes 01 in b", d.What)
+}
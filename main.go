@@ -1,42 +1,326 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
 
+	"github.com/bchadwic/chip8/debugger"
 	"github.com/bchadwic/chip8/emulator"
+	"github.com/bchadwic/chip8/internal/display"
+	"github.com/bchadwic/chip8/internal/drivers"
+	"github.com/bchadwic/chip8/internal/frontend"
+	"github.com/bchadwic/chip8/internal/keymap"
+	"github.com/bchadwic/chip8/internal/keypad"
+	"github.com/bchadwic/chip8/internal/terminal"
+	"github.com/bchadwic/chip8/movie"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+
 	settings := &emulator.EmulatorSettings{}
 
-	flag.IntVar(&settings.FrameRate, "r", 4, "frame refresh rate")
-	flag.BoolVar(&settings.Fill, "l", true, "color fill pixels")
+	frameRate := flag.Int("r", 4, "frame refresh rate")
+	flag.IntVar(&settings.InstructionsPerSecond, "ips", emulator.DEFAULT_IPS, "instructions executed per second")
+	fill := flag.Bool("l", true, "color fill pixels")
 	flag.StringVar(&settings.Color, "c", "white", "color of pixels")
-	// sorry, dvorak is my default... eventually deprecating this flag for a keymap file would be best
-	flag.StringVar(&settings.Keyboard, "k", "dvorak", "type of keyboard (dvorak, qwerty)")
+	// sorry, dvorak is my default... -keymap replaces it when set
+	keyboard := flag.String("k", "dvorak", "type of keyboard (dvorak, qwerty)")
+	keymapFile := flag.String("keymap", "", "json file binding host keys to keypad keys, replacing -k")
+	fe := flag.String("frontend", "window", "how the emulator is shown ("+strings.Join(FRONTENDS, ", ")+")")
+	flag.BoolVar(&settings.Headless, "headless", false, "run without a frontend, the same as -frontend=headless")
+	flag.Uint64Var(&settings.Seed, "seed", 0, "seed for random numbers, the same seed replays the same game (0 picks one from the clock)")
+	fault := flag.String("fault", "halt", "what to do when an instruction faults (halt, skip, trap), trap needs -debug")
+	platform := flag.String("platform", "chip8", "instruction set to run (chip8, schip, xochip)")
+	quirks := flag.String("quirks", "", "quirks preset ("+strings.Join(emulator.QuirksPresets(), ", ")+"), defaults to the platform's")
+	// individual quirks override the preset when set
+	shift := flag.Bool("quirk-shift", false, "8XY6/8XYE shift vy into vx")
+	memory := flag.Bool("quirk-memory", false, "FX55/FX65 increment i")
+	jump := flag.Bool("quirk-jump", false, "BNNN jumps to nnn + vx")
+	vfReset := flag.Bool("quirk-vfreset", false, "8XY1/8XY2/8XY3 reset vf")
+	wrap := flag.Bool("quirk-wrap", false, "DXYN wraps sprites rather than clipping them")
+	displayWait := flag.Bool("quirk-vblank", false, "DXYN waits for the next frame")
+	loadState := flag.String("load-state", "", "save state to start from, F5 and F9 save and load it (defaults to the rom path with .state appended)")
+	flag.IntVar(&settings.ScreenshotScale, "screenshot-scale", display.SCALE, "width in real pixels of a pixel in screenshots taken with F12")
+	record := flag.String("record", "", "record the screen to a .gif or .y4m file until the emulator exits, F10 starts and stops a gif while playing")
+	rewind := flag.Int("rewind", 10, "seconds of history kept for rewinding with backspace, 0 disables rewind")
+	recordMovie := flag.String("movie-record", "", "record the keys pressed each frame to a movie file, loading states and rewinding are turned off")
+	playMovie := flag.String("movie-play", "", "play back a movie file, replacing the rom's settings with the recorded ones, loading states and rewinding are turned off")
+	trace := flag.String("trace", "", "write every executed instruction to a file, - for stderr")
+	traceAddr := flag.String("trace-addr", "", "only trace these addresses, such as 0x200-0x2FF,0x310")
+	traceOps := flag.String("trace-ops", "", "only trace these opcode classes by first nibble, such as D,F")
+	traceMax := flag.Int("trace-max", 0, "stop tracing after this many instructions, 0 for no limit")
+	debug := flag.Bool("debug", false, "start paused in an interactive debugger reading from stdin, faults trap unless -fault is set")
 	flag.Parse()
 
+	policy, err := emulator.ParseFaultPolicy(*fault)
+	if err != nil || policy == emulator.FAULT_CALLBACK {
+		log.Fatalf("invalid fault policy: %s", *fault)
+	}
+	// nothing but the debugger can resume a trapped machine
+	if policy == emulator.FAULT_TRAP && !*debug {
+		log.Fatal("the trap fault policy needs -debug to resume the machine")
+	}
+	settings.FaultPolicy = policy
+	km, err := loadKeymap(*keymapFile, *keyboard, filepath.Base(flag.Arg(0)))
+	if err != nil {
+		log.Fatal(err)
+	}
+	settings.Frontend, err = createFrontend(*fe, *frameRate, *fill, settings.Color, km)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *debug && *fe == "terminal" && !settings.Headless {
+		log.Fatal("the debugger and terminal frontend can not share stdin")
+	}
+	if *debug && !isSet("fault") {
+		settings.FaultPolicy = emulator.FAULT_TRAP
+	}
+
+	settings.Platform, err = emulator.ParsePlatform(*platform)
+	if err != nil {
+		log.Fatal(err)
+	}
+	settings.Quirks = settings.Platform.Quirks()
+	if *quirks != "" {
+		settings.Quirks, err = emulator.QuirksPreset(*quirks)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "quirk-shift":
+			settings.Quirks.Shift = *shift
+		case "quirk-memory":
+			settings.Quirks.MemoryIncrement = *memory
+		case "quirk-jump":
+			settings.Quirks.Jump = *jump
+		case "quirk-vfreset":
+			settings.Quirks.VFReset = *vfReset
+		case "quirk-wrap":
+			settings.Quirks.Wrap = *wrap
+		case "quirk-vblank":
+			settings.Quirks.DisplayWait = *displayWait
+		}
+	})
+
 	fname := flag.Arg(0)
 	if fname == "" {
 		log.Fatal("rom file not specified")
 	}
+	rom, err := readROM(fname)
+	if err != nil {
+		log.Fatal(err)
+	}
+	settings.RewindFrames = *rewind * emulator.TIMER_HZ
+	settings.StatePath = fname + ".state"
+	settings.ScreenshotPath = strings.TrimSuffix(fname, filepath.Ext(fname))
+	settings.RecordingPath = settings.ScreenshotPath
+	if *loadState != "" {
+		settings.StatePath = *loadState
+	}
+	if *trace != "" {
+		settings.Trace, err = createTrace(*trace, *traceAddr, *traceOps, *traceMax)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	stopMovie, err := startMovie(settings, rom, *recordMovie, *playMovie)
+	if err != nil {
+		log.Fatal(err)
+	}
+	em := emulator.Create(settings)
+	if err := em.LoadROM(rom); err != nil {
+		log.Fatalf("could not load rom: %v", err)
+	}
+	if *loadState != "" {
+		f, err := os.Open(*loadState)
+		if err != nil {
+			log.Fatalf("could not open save state: %v", err)
+		}
+		err = em.LoadState(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("could not load save state: %v", err)
+		}
+	}
+	// ctrl+c stops the machine, even while FX0A waits on a key,
+	// so the frontend is closed and recordings are finished
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	// quitting the debugger stops the machine the same way
+	run, quit := context.WithCancel(ctx)
+	defer quit()
+	if *debug {
+		d := debugger.Create(em, os.Stdin, os.Stdout)
+		em.Pause()
+		go func() {
+			if err := d.Run(); err != nil {
+				log.Print(err)
+			}
+			quit()
+		}()
+	}
+	if *record != "" {
+		if err := em.StartRecording(*record); err != nil {
+			log.Fatalf("could not record: %v", err)
+		}
+	}
+	stop := func() {
+		stopMovie()
+		if err := em.StopRecording(); err != nil {
+			log.Printf("could not finish recording: %v", err)
+		}
+	}
+	err = em.StartContext(run)
+	stop()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if ctx.Err() != nil {
+		os.Exit(1)
+	}
+}
+
+// FRONTENDS can be picked with -frontend
+var FRONTENDS = []string{"window", "terminal", "headless"}
+
+// createFrontend builds the frontend named by -frontend
+func createFrontend(name string, frameRate int, fill bool, color string, keyboard keymap.Keymap) (frontend.Frontend, error) {
+	switch name {
+	case "window":
+		return drivers.Create().
+			KeypadSettings(keyboard).
+			DisplaySettings(frameRate, fill, color), nil
+	case "terminal":
+		return terminal.Create().
+			KeypadSettings(keyboard).
+			DisplaySettings(color), nil
+	case "headless":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown frontend %q, expected one of %s", name, strings.Join(FRONTENDS, ", "))
+}
+
+// loadKeymap reads the keys bound for rom from the keymap file at
+// path, or the built in keyboard layout when there is no file
+func loadKeymap(path, keyboard, rom string) (keymap.Keymap, error) {
+	if path == "" {
+		return keymap.Layout(keyboard), nil
+	}
+	f, err := keymap.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keymap:\n%v", err)
+	}
+	return f.For(rom), nil
+}
+
+// createTrace builds a trace writing to path, the file
+// is left open until the program exits
+func createTrace(path, addrs, ops string, max int) (*emulator.Trace, error) {
+	t := &emulator.Trace{W: os.Stderr, Max: max}
+	var err error
+	if t.Ranges, err = emulator.ParseAddrRanges(addrs); err != nil {
+		return nil, err
+	}
+	if t.Classes, err = emulator.ParseOpcodeClasses(ops); err != nil {
+		return nil, err
+	}
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not create trace file: %v", err)
+		}
+		t.W = f
+	}
+	return t, nil
+}
+
+// startMovie sets up recording or playing back a movie, returning
+// a function that finishes writing the recording. Movies only hold
+// the keys pressed, so loading states and rewinding are turned off
+func startMovie(settings *emulator.EmulatorSettings, rom []uint8, record, play string) (func(), error) {
+	switch {
+	case record != "" && play != "":
+		return nil, fmt.Errorf("can not record and play a movie at the same time")
+	case (record != "" || play != "") && isSet("load-state"):
+		return nil, fmt.Errorf("can not start a movie from a save state")
+	case (record != "" || play != "") && isSet("rewind") && settings.RewindFrames > 0:
+		return nil, fmt.Errorf("can not rewind while recording or playing a movie")
+	case play != "":
+		f, err := os.Open(play)
+		if err != nil {
+			return nil, fmt.Errorf("could not open movie: %v", err)
+		}
+		defer f.Close()
+		m, err := movie.Read(f)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.Settings.Apply(settings, rom); err != nil {
+			return nil, err
+		}
+		settings.Keypad = movie.Play(m)
+		settings.Movie, settings.RewindFrames = true, 0
+	case record != "":
+		f, err := os.Create(record)
+		if err != nil {
+			return nil, fmt.Errorf("could not create movie: %v", err)
+		}
+		recorder, err := movie.Record(keypad.Create(), f, movie.Capture(settings, rom))
+		if err != nil {
+			return nil, err
+		}
+		settings.Keypad = recorder
+		settings.Movie, settings.RewindFrames = true, 0
+		return func() {
+			if err := recorder.Close(); err != nil {
+				log.Printf("could not record movie: %v", err)
+			}
+			f.Close()
+		}, nil
+	}
+	return func() {}, nil
+}
+
+// isSet reports whether the flag was given on the command line
+func isSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
+
+// readROM reads the entire rom file
+func readROM(fname string) ([]uint8, error) {
 	f, err := os.Open(fname)
 	if err != nil {
-		log.Fatalf("could not open rom file: %v", err)
+		return nil, fmt.Errorf("could not open rom file: %v", err)
 	}
 	defer f.Close()
 	fi, err := f.Stat()
 	if err != nil {
-		log.Fatalf("could not determine rom size: %v", err)
+		return nil, fmt.Errorf("could not determine rom size: %v", err)
 	}
 	rom := make([]uint8, fi.Size())
 	_, err = f.Read(rom)
 	if err != nil {
-		log.Fatalf("could not read rom file: %v", err)
+		return nil, fmt.Errorf("could not read rom file: %v", err)
 	}
-	em := emulator.Create(settings)
-	em.Load(rom)
-	em.Start()
+	return rom, nil
 }
@@ -0,0 +1,227 @@
+package movie
+
+import (
+	"io"
+	"sync"
+
+	"github.com/bchadwic/chip8/internal/keypad"
+)
+
+// Recorder is a keypad that passes input from a live keypad through
+// to the machine a frame at a time, writing what it passes to a movie
+type Recorder struct {
+	live keypad.Keypad
+	w    io.Writer
+
+	mu sync.Mutex
+	// keys the machine sees this frame
+	pressed uint16
+	// frames with the same keys, written once the keys change
+	run Event
+	err error
+}
+
+// Record starts a movie on w, input is read from live
+func Record(live keypad.Keypad, w io.Writer, settings Settings) (*Recorder, error) {
+	if err := writeHeader(w, settings); err != nil {
+		return nil, err
+	}
+	return &Recorder{live: live, w: w}, nil
+}
+
+// Frame latches the live keys for the frame about to run
+func (r *Recorder) Frame() {
+	if framer, ok := r.live.(keypad.Framer); ok {
+		framer.Frame()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pressed = r.live.State()
+	if r.run.Frames > 0 && r.run.Keys == r.pressed {
+		r.run.Frames++
+		return
+	}
+	r.flush()
+	r.run = Event{Keys: r.pressed, Frames: 1}
+}
+
+// flush writes the current run
+func (r *Recorder) flush() {
+	if r.run.Frames > 0 && r.err == nil {
+		r.err = writeEvent(r.w, r.run)
+	}
+	r.run.Frames = 0
+}
+
+func (r *Recorder) Clear() {
+	r.mu.Lock()
+	r.pressed = 0
+	r.mu.Unlock()
+	r.live.Clear()
+}
+
+func (r *Recorder) Get(kaddr uint8) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return kaddr < 16 && r.pressed&(1<<kaddr) != 0
+}
+
+func (r *Recorder) Press(kaddr uint8) {
+	r.live.Press(kaddr)
+}
+
+func (r *Recorder) Release(kaddr uint8) {
+	r.live.Release(kaddr)
+}
+
+// Await starts a wait on the live keypad
+func (r *Recorder) Await() {
+	r.live.Await()
+}
+
+// Awaited records the key that ended the wait on the frame
+// the machine received it, so it is played back on the same one
+func (r *Recorder) Awaited() (uint8, bool) {
+	kaddr, ok := r.live.Awaited()
+	if !ok {
+		return 0, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// the run is split so the wait lands where it happened
+	keys := r.run.Keys
+	r.flush()
+	r.run.Keys = keys
+	if r.err == nil {
+		r.err = writeEvent(r.w, Event{Wait: true, Key: kaddr})
+	}
+	return kaddr, true
+}
+
+func (r *Recorder) State() uint16 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pressed
+}
+
+func (r *Recorder) Restore(state uint16) {
+	r.mu.Lock()
+	r.pressed = state
+	r.mu.Unlock()
+	r.live.Restore(state)
+}
+
+// Close writes the last run of frames, returning
+// the first error encountered while recording
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.flush()
+	return r.err
+}
+
+// Player is a keypad that plays back the input of a movie,
+// keys set from the window are ignored
+type Player struct {
+	mu sync.Mutex
+	// keys pressed on every frame
+	frames []uint16
+	frame  int
+	// keys received by FX0A in order
+	waits   []wait
+	pressed uint16
+	// set while FX0A waits for the next recorded key
+	waiting bool
+	// the key that ended the last wait, -1 until one does
+	awaited int
+}
+
+// wait is a key received by FX0A on a frame
+type wait struct {
+	frame int
+	key   uint8
+}
+
+// Play creates a keypad that replays m
+func Play(m *Movie) *Player {
+	p := &Player{awaited: -1}
+	for _, e := range m.Events {
+		if e.Wait {
+			p.waits = append(p.waits, wait{frame: len(p.frames), key: e.Key})
+			continue
+		}
+		for f := 0; f < e.Frames; f++ {
+			p.frames = append(p.frames, e.Keys)
+		}
+	}
+	return p
+}
+
+// Frame moves on to the keys of the next frame, once
+// the movie is over no keys are pressed
+func (p *Player) Frame() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pressed = 0
+	if p.frame < len(p.frames) {
+		p.pressed = p.frames[p.frame]
+	}
+	p.frame++
+}
+
+// Done reports whether every frame of the movie has been played
+func (p *Player) Done() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.frame >= len(p.frames)
+}
+
+func (p *Player) Clear() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pressed = 0
+}
+
+func (p *Player) Get(kaddr uint8) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return kaddr < 16 && p.pressed&(1<<kaddr) != 0
+}
+
+func (p *Player) Press(kaddr uint8)   {}
+func (p *Player) Release(kaddr uint8) {}
+
+// Await starts a wait for the next key FX0A received in the recording,
+// Awaited returns it from the frame it was received. Once there are
+// none left the wait never ends, like a player who stopped
+func (p *Player) Await() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.waiting, p.awaited = true, -1
+}
+
+func (p *Player) Awaited() (uint8, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.waiting && len(p.waits) > 0 && p.waits[0].frame <= p.frame {
+		p.awaited = int(p.waits[0].key)
+		p.waits = p.waits[1:]
+		p.waiting = false
+	}
+	if p.awaited < 0 {
+		return 0, false
+	}
+	return uint8(p.awaited), true
+}
+
+func (p *Player) State() uint16 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pressed
+}
+
+func (p *Player) Restore(state uint16) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pressed = state
+}
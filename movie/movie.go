@@ -0,0 +1,201 @@
+// Package movie records the input of a play session along with the
+// settings needed to replay it, so the session can be played back
+// exactly. Input is taken a frame at a time through keypads that
+// wrap or replace the one the window sets.
+//
+// Movies are text, a header followed by one event per line:
+//
+//	chip8-movie 1
+//	settings {"rom":"…","seed":42,"platform":"chip8","ips":700,"quirks":{…}}
+//	keys 0000 120   ; no keys pressed for 120 frames
+//	keys 0010 4     ; key 4 pressed for 4 frames
+//	wait 4          ; FX0A received key 4
+package movie
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bchadwic/chip8/emulator"
+)
+
+const (
+	MAGIC = "chip8-movie"
+	// bumped whenever the format changes
+	VERSION = 1
+)
+
+var ErrROM = errors.New("movie was recorded with a different rom")
+
+// Settings are everything that affects how a recording plays out
+type Settings struct {
+	ROM      string          `json:"rom"`
+	Seed     uint64          `json:"seed"`
+	Platform string          `json:"platform"`
+	IPS      int             `json:"ips"`
+	Quirks   emulator.Quirks `json:"quirks"`
+}
+
+// Event is either a run of frames with the same keys pressed,
+// or a key received while waiting in FX0A
+type Event struct {
+	// bitmask of pressed keys, key n is bit n
+	Keys   uint16
+	Frames int
+
+	Wait bool
+	Key  uint8
+}
+
+type Movie struct {
+	Settings Settings
+	Events   []Event
+}
+
+// Capture returns the settings of a machine about to be recorded, a
+// seed is picked when settings has none so the movie and machine agree
+func Capture(settings *emulator.EmulatorSettings, rom []uint8) Settings {
+	if settings.Seed == 0 {
+		settings.Seed = uint64(time.Now().UnixNano())
+	}
+	ips := settings.InstructionsPerSecond
+	if ips <= 0 {
+		ips = emulator.DEFAULT_IPS
+	}
+	return Settings{
+		ROM:      romHash(rom),
+		Seed:     settings.Seed,
+		Platform: settings.Platform.String(),
+		IPS:      ips,
+		Quirks:   settings.Quirks,
+	}
+}
+
+// Apply configures settings to replay the movie, returning
+// ErrROM if the movie was not recorded with rom
+func (s Settings) Apply(settings *emulator.EmulatorSettings, rom []uint8) error {
+	if s.ROM != romHash(rom) {
+		return ErrROM
+	}
+	platform, err := emulator.ParsePlatform(s.Platform)
+	if err != nil {
+		return err
+	}
+	settings.Seed = s.Seed
+	settings.Platform = platform
+	settings.InstructionsPerSecond = s.IPS
+	settings.Quirks = s.Quirks
+	return nil
+}
+
+func romHash(rom []uint8) string {
+	sum := sha256.Sum256(rom)
+	return hex.EncodeToString(sum[:])
+}
+
+// Frames returns the number of frames the movie lasts
+func (m *Movie) Frames() int {
+	frames := 0
+	for _, e := range m.Events {
+		frames += e.Frames
+	}
+	return frames
+}
+
+// Write writes the whole movie to w
+func (m *Movie) Write(w io.Writer) error {
+	if err := writeHeader(w, m.Settings); err != nil {
+		return err
+	}
+	for _, e := range m.Events {
+		if err := writeEvent(w, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeHeader(w io.Writer, s Settings) error {
+	settings, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s %d\nsettings %s\n", MAGIC, VERSION, settings)
+	return err
+}
+
+func writeEvent(w io.Writer, e Event) error {
+	var err error
+	if e.Wait {
+		_, err = fmt.Fprintf(w, "wait %X\n", e.Key)
+	} else {
+		_, err = fmt.Fprintf(w, "keys %04X %d\n", e.Keys, e.Frames)
+	}
+	return err
+}
+
+// Read parses a movie, errors include the line they were found on
+func Read(r io.Reader) (*Movie, error) {
+	m := &Movie{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	fail := func(format string, args ...any) (*Movie, error) {
+		return nil, fmt.Errorf("movie line %d: %s", line, fmt.Sprintf(format, args...))
+	}
+
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if c := strings.IndexByte(text, ';'); c >= 0 {
+			text = text[:c]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case line == 1:
+			if len(fields) != 2 || fields[0] != MAGIC {
+				return fail("not a movie")
+			}
+			if fields[1] != strconv.Itoa(VERSION) {
+				return fail("unsupported version %s", fields[1])
+			}
+		case fields[0] == "settings":
+			if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(text, "settings"))), &m.Settings); err != nil {
+				return fail("invalid settings: %v", err)
+			}
+		case fields[0] == "keys" && len(fields) == 3:
+			keys, err := strconv.ParseUint(fields[1], 16, 16)
+			if err != nil {
+				return fail("invalid keys %s", fields[1])
+			}
+			frames, err := strconv.Atoi(fields[2])
+			if err != nil || frames < 1 {
+				return fail("invalid frame count %s", fields[2])
+			}
+			m.Events = append(m.Events, Event{Keys: uint16(keys), Frames: frames})
+		case fields[0] == "wait" && len(fields) == 2:
+			key, err := strconv.ParseUint(fields[1], 16, 4)
+			if err != nil {
+				return fail("invalid key %s", fields[1])
+			}
+			m.Events = append(m.Events, Event{Wait: true, Key: uint8(key)})
+		default:
+			return fail("unknown event %q", text)
+		}
+	}
+	if line == 0 {
+		return nil, errors.New("movie is empty")
+	}
+	return m, scanner.Err()
+}
@@ -0,0 +1,127 @@
+package movie
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bchadwic/chip8/emulator"
+	"github.com/bchadwic/chip8/internal/keypad"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Read(t *testing.T) {
+	src := "chip8-movie 1\n" +
+		`settings {"rom":"abc","seed":42,"platform":"schip","ips":700,"quirks":{"Shift":true}}` + "\n" +
+		"keys 0000 120 ; idle\n" +
+		"keys 0010 4\n" +
+		"wait 4\n"
+	m, err := Read(strings.NewReader(src))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(42), m.Settings.Seed)
+	assert.True(t, m.Settings.Quirks.Shift)
+	assert.Equal(t, []Event{
+		{Keys: 0x0000, Frames: 120},
+		{Keys: 0x0010, Frames: 4},
+		{Wait: true, Key: 4},
+	}, m.Events)
+	assert.Equal(t, 124, m.Frames())
+
+	var out bytes.Buffer
+	assert.NoError(t, m.Write(&out))
+	again, err := Read(&out)
+	assert.NoError(t, err)
+	assert.Equal(t, m, again)
+}
+
+func Test_Read_errors(t *testing.T) {
+	_, err := Read(strings.NewReader("chip8-movie 2\n"))
+	assert.EqualError(t, err, "movie line 1: unsupported version 2")
+	_, err = Read(strings.NewReader("chip8-movie 1\nkeys 0000 0\n"))
+	assert.EqualError(t, err, "movie line 2: invalid frame count 0")
+	_, err = Read(strings.NewReader("chip8-movie 1\njump\n"))
+	assert.EqualError(t, err, `movie line 2: unknown event "jump"`)
+}
+
+func Test_Apply(t *testing.T) {
+	s := Settings{ROM: romHash([]uint8{1}), Seed: 3, Platform: "xochip", IPS: 1000}
+	settings := &emulator.EmulatorSettings{}
+	assert.NoError(t, s.Apply(settings, []uint8{1}))
+	assert.Equal(t, emulator.PLATFORM_XOCHIP, settings.Platform)
+	assert.Equal(t, uint64(3), settings.Seed)
+	assert.True(t, errors.Is(s.Apply(settings, []uint8{2}), ErrROM))
+}
+
+// a movie recorded from live input replays to exactly the same machine
+func Test_RecordPlay(t *testing.T) {
+	rom, err := os.ReadFile("../roms/pong.ch8")
+	assert.NoError(t, err)
+
+	live := keypad.Create()
+	settings := &emulator.EmulatorSettings{Headless: true}
+	var out bytes.Buffer
+	recorder, err := Record(live, &out, Capture(settings, rom))
+	assert.NoError(t, err)
+	assert.NotZero(t, settings.Seed)
+	settings.Keypad = recorder
+	recorded := emulator.Create(settings)
+	assert.NoError(t, recorded.LoadROM(rom))
+
+	for f := 0; f < 600; f++ {
+		// hold the paddle keys down now and then
+		if f%50 == 0 {
+			live.Press(0x1)
+		} else if f%50 == 20 {
+			live.Release(0x1)
+		}
+		if f%70 == 51 {
+			live.Press(0x4)
+		} else if f%70 == 0 {
+			live.Release(0x4)
+		}
+		assert.NoError(t, recorded.Run(1))
+	}
+	assert.NoError(t, recorder.Close())
+
+	m, err := Read(&out)
+	assert.NoError(t, err)
+	assert.Equal(t, 600, m.Frames())
+	assert.Greater(t, len(m.Events), 10)
+
+	player := Play(m)
+	replay := &emulator.EmulatorSettings{Headless: true, Keypad: player}
+	assert.NoError(t, m.Settings.Apply(replay, rom))
+	played := emulator.Create(replay)
+	assert.NoError(t, played.LoadROM(rom))
+	for !player.Done() {
+		assert.NoError(t, played.Run(1))
+	}
+
+	assert.Equal(t, recorded.Registers(), played.Registers())
+	assert.Equal(t, recorded.PC(), played.PC())
+	assert.Equal(t, recorded.Display().Pixels(), played.Display().Pixels())
+}
+
+func Test_Player_Await(t *testing.T) {
+	p := Play(&Movie{Events: []Event{{Keys: 0x0002, Frames: 1}, {Wait: true, Key: 0xB}}})
+	// the key is received on the frame it was recorded on
+	p.Await()
+	_, ok := p.Awaited()
+	assert.False(t, ok)
+	p.Frame()
+	assert.True(t, p.Get(0x1))
+	key, ok := p.Awaited()
+	assert.True(t, ok)
+	assert.Equal(t, uint8(0xB), key)
+	p.Frame()
+	assert.False(t, p.Get(0x1))
+	assert.True(t, p.Done())
+
+	// no keys are left, so the wait goes on
+	p.Await()
+	p.Frame()
+	_, ok = p.Awaited()
+	assert.False(t, ok)
+}
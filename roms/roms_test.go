@@ -0,0 +1,61 @@
+package roms
+
+import (
+	"testing"
+
+	"github.com/bchadwic/chip8/golden"
+)
+
+func Test_IBM(t *testing.T) {
+	d := golden.Run{Frames: 60}.ROM(t, "ibm.ch8")
+	golden.AssertText(t, "testdata/ibm.txt", d)
+	golden.AssertPNG(t, "testdata/ibm.png", d)
+}
+
+func Test_Maze(t *testing.T) {
+	d := golden.Run{Frames: 120}.ROM(t, "maze.ch8")
+	golden.AssertText(t, "testdata/maze.txt", d)
+}
+
+func Test_Stars(t *testing.T) {
+	d := golden.Run{Frames: 120}.ROM(t, "stars.ch8")
+	golden.AssertText(t, "testdata/stars.txt", d)
+}
+
+func Test_Pong(t *testing.T) {
+	// the left paddle moves up then down
+	d := golden.Run{
+		Frames: 180,
+		Input: []golden.Input{
+			{Frames: 30},
+			{Keys: []uint8{0x1}, Frames: 20},
+			{Frames: 30},
+			{Keys: []uint8{0x4}, Frames: 40},
+		},
+	}.ROM(t, "pong.ch8")
+	golden.AssertText(t, "testdata/pong.txt", d)
+}
+
+func Test_Tetris(t *testing.T) {
+	// the first piece is moved left and dropped
+	d := golden.Run{
+		Frames: 240,
+		Input: []golden.Input{
+			{Frames: 30},
+			{Keys: []uint8{0x5}, Frames: 20},
+			{Frames: 10},
+			{Keys: []uint8{0x7}, Frames: 60},
+		},
+	}.ROM(t, "tetris.ch8")
+	golden.AssertText(t, "testdata/tetris.txt", d)
+}
+
+func Test_TTT(t *testing.T) {
+	// squares are picked through FX0A, enough keys are
+	// given that the rom never waits on one that is not there
+	d := golden.Run{
+		Frames: 60,
+		Waits:  []uint8{0x5, 0x1, 0x9, 0x2, 0x3, 0x7, 0x4, 0x6, 0x8},
+	}.ROM(t, "ttt.ch8")
+	golden.AssertText(t, "testdata/ttt.txt", d)
+}